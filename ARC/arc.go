@@ -0,0 +1,352 @@
+// Package arc 实现自适应替换缓存（Adaptive Replacement Cache），接口与 lru 相同，可作为 cache.Cache 的淘汰策略。
+// 常驻项分为两个列表：T1 保存只访问过一次的项，T2 保存至少访问过两次的项；
+// 被淘汰项的 key 记录在对应的 ghost 列表 B1、B2 中。再次写入命中 B1 说明 T1 偏小，命中 B2 说明 T2 偏小，
+// 据此自动调整 T1 的目标大小，使缓存在最近性与频率之间自适应，一次性扫描不会把频繁访问的 key 挤出缓存。
+// 容量按字节计算，与 lru 一致。
+package arc
+
+import (
+	"container/list"
+	"fmt"
+	lru "geecache/LRU"
+	"sort"
+)
+
+type Cache struct {
+	maxBytes int64
+	p        int64 // T1 的目标字节数，随 ghost 命中在 [0, maxBytes] 内调整
+
+	t1, t2 *list.List // 常驻项，表头为最近使用
+	b1, b2 *list.List // ghost，只记录 key 与大小，表头为最近淘汰
+
+	t1Bytes, t2Bytes int64
+	b1Bytes, b2Bytes int64
+
+	items  map[string]*list.Element // 常驻项
+	ghosts map[string]*list.Element // ghost
+	pinned map[string]struct{}
+	counts map[lru.Priority]int // 各优先级的常驻项数
+
+	OnEvicted func(key string, value lru.Value, reason lru.EvictReason)
+}
+
+type entry struct {
+	key      string
+	value    lru.Value
+	priority lru.Priority
+	frequent bool // 在 T2 中
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key)) + int64(e.value.Len())
+}
+
+type ghost struct {
+	key      string
+	size     int64
+	frequent bool // 在 B2 中
+}
+
+func New(maxBytes int64, onEvicted func(key string, value lru.Value, reason lru.EvictReason)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		t1:        list.New(),
+		t2:        list.New(),
+		b1:        list.New(),
+		b2:        list.New(),
+		items:     make(map[string]*list.Element),
+		ghosts:    make(map[string]*list.Element),
+		counts:    make(map[lru.Priority]int),
+		OnEvicted: onEvicted,
+	}
+}
+
+// promote 记录一次命中：项移到 T2 表头
+func (c *Cache) promote(element *list.Element) *list.Element {
+	e := element.Value.(*entry)
+	if e.frequent {
+		c.t2.MoveToFront(element)
+		return element
+	}
+	c.t1.Remove(element)
+	c.t1Bytes -= e.size()
+	e.frequent = true
+	c.t2Bytes += e.size()
+	element = c.t2.PushFront(e)
+	c.items[e.key] = element
+	return element
+}
+
+func (c *Cache) Get(key string) (lru.Value, bool) {
+	if element, ok := c.items[key]; ok {
+		return c.promote(element).Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+// Peek 返回 key 对应的值，不计入访问
+func (c *Cache) Peek(key string) (lru.Value, bool) {
+	if element, ok := c.items[key]; ok {
+		return element.Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+// Range 先遍历 T2 再遍历 T1，各自从最近使用到最久未使用，fn 返回 false 时停止遍历
+func (c *Cache) Range(fn func(key string, value lru.Value) bool) {
+	for _, l := range []*list.List{c.t2, c.t1} {
+		for element := l.Front(); element != nil; element = element.Next() {
+			e := element.Value.(*entry)
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Add 写入缓存项，新项使用 lru.PriorityNormal，已存在的项保留原有优先级
+func (c *Cache) Add(key string, value lru.Value) {
+	priority := lru.PriorityNormal
+	if element, ok := c.items[key]; ok {
+		priority = element.Value.(*entry).priority
+	}
+	c.AddWithPriority(key, value, priority)
+}
+
+// AddWithPriority 以指定的淘汰优先级写入缓存项，低优先级的项总是先于高优先级的项被淘汰。
+// 已存在的项视为一次命中；命中 ghost 的 key 会调整 T1 的目标大小并直接进入 T2。
+func (c *Cache) AddWithPriority(key string, value lru.Value, priority lru.Priority) {
+	if element, ok := c.items[key]; ok {
+		element = c.promote(element)
+		e := element.Value.(*entry)
+		c.t2Bytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		c.uncount(e.priority)
+		e.priority = priority
+		c.counts[priority]++
+		c.shrink(false)
+		return
+	}
+
+	e := &entry{key: key, value: value, priority: priority}
+	fromB2 := false
+	if element, ok := c.ghosts[key]; ok {
+		g := element.Value.(*ghost)
+		c.adapt(g, e.size())
+		c.removeGhost(element)
+		e.frequent = true
+		fromB2 = g.frequent
+	}
+	if e.frequent {
+		c.items[key] = c.t2.PushFront(e)
+		c.t2Bytes += e.size()
+	} else {
+		c.items[key] = c.t1.PushFront(e)
+		c.t1Bytes += e.size()
+	}
+	c.counts[priority]++
+	c.shrink(fromB2)
+}
+
+// adapt 按命中的 ghost 调整 p：命中 B1 时增大 T1 的目标，命中 B2 时减小，
+// 对方 ghost 列表越长调整幅度越大
+func (c *Cache) adapt(g *ghost, size int64) {
+	if !g.frequent {
+		delta := size
+		if c.b1.Len() > 0 && c.b2.Len() > c.b1.Len() {
+			delta *= int64(c.b2.Len() / c.b1.Len())
+		}
+		c.p = min(c.p+delta, c.maxBytes)
+		return
+	}
+	delta := size
+	if c.b2.Len() > 0 && c.b1.Len() > c.b2.Len() {
+		delta *= int64(c.b1.Len() / c.b2.Len())
+	}
+	c.p = max(c.p-delta, 0)
+}
+
+// RemoveOldest 按 ARC 的规则淘汰一个未被固定的项
+func (c *Cache) RemoveOldest() {
+	c.evict(false)
+}
+
+// shrink 淘汰缓存项直到满足容量上限或没有可淘汰的项，再裁剪 ghost 列表。
+// fromB2 表示本次写入命中了 B2，此时 T1 恰好达到目标大小也优先从 T1 淘汰
+func (c *Cache) shrink(fromB2 bool) {
+	if c.maxBytes == 0 {
+		return
+	}
+	for c.t1Bytes+c.t2Bytes > c.maxBytes {
+		if !c.evict(fromB2) {
+			break
+		}
+	}
+	// T1 与 B1 合计不超过容量，全部 ghost 与常驻项合计不超过两倍容量
+	for c.t1Bytes+c.b1Bytes > c.maxBytes && c.b1.Len() > 0 {
+		c.removeGhost(c.b1.Back())
+	}
+	for c.t1Bytes+c.t2Bytes+c.b1Bytes+c.b2Bytes > 2*c.maxBytes && c.b2.Len() > 0 {
+		c.removeGhost(c.b2.Back())
+	}
+}
+
+// evict 淘汰最低优先级中按 ARC 规则选出的项，没有可淘汰的项时返回 false
+func (c *Cache) evict(fromB2 bool) bool {
+	// 只有一种优先级时（默认情况）就是普通的 ARC
+	if len(c.counts) <= 1 {
+		return c.evictPriority(nil, fromB2)
+	}
+	priorities := make([]lru.Priority, 0, len(c.counts))
+	for p := range c.counts {
+		priorities = append(priorities, p)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+	for _, p := range priorities {
+		if c.evictPriority(&p, fromB2) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictPriority 在 T1 超出目标大小时先从 T1 尾部淘汰，否则先从 T2 尾部淘汰，
+// 首选列表中没有符合条件的项时再尝试另一个列表。p 为 nil 时不限优先级
+func (c *Cache) evictPriority(p *lru.Priority, fromB2 bool) bool {
+	first, second := c.t2, c.t1
+	if c.t1.Len() > 0 && (c.t1Bytes > c.p || (fromB2 && c.t1Bytes == c.p)) {
+		first, second = c.t1, c.t2
+	}
+	for _, l := range []*list.List{first, second} {
+		for element := l.Back(); element != nil; element = element.Prev() {
+			e := element.Value.(*entry)
+			if p != nil && e.priority != *p {
+				continue
+			}
+			if _, ok := c.pinned[e.key]; ok {
+				continue
+			}
+			c.removeElement(element, lru.ReasonCapacity)
+			c.addGhost(e)
+			return true
+		}
+	}
+	return false
+}
+
+// addGhost 记录被容量淘汰的项：T1 的项进入 B1，T2 的项进入 B2
+func (c *Cache) addGhost(e *entry) {
+	g := &ghost{key: e.key, size: e.size(), frequent: e.frequent}
+	if g.frequent {
+		c.ghosts[g.key] = c.b2.PushFront(g)
+		c.b2Bytes += g.size
+	} else {
+		c.ghosts[g.key] = c.b1.PushFront(g)
+		c.b1Bytes += g.size
+	}
+}
+
+func (c *Cache) removeGhost(element *list.Element) {
+	g := element.Value.(*ghost)
+	delete(c.ghosts, g.key)
+	if g.frequent {
+		c.b2.Remove(element)
+		c.b2Bytes -= g.size
+	} else {
+		c.b1.Remove(element)
+		c.b1Bytes -= g.size
+	}
+}
+
+// removeElement 删除常驻项并触发 OnEvicted
+func (c *Cache) removeElement(element *list.Element, reason lru.EvictReason) {
+	e := element.Value.(*entry)
+	if e.frequent {
+		c.t2.Remove(element)
+		c.t2Bytes -= e.size()
+	} else {
+		c.t1.Remove(element)
+		c.t1Bytes -= e.size()
+	}
+	delete(c.items, e.key)
+	c.uncount(e.priority)
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value, reason)
+	}
+}
+
+func (c *Cache) uncount(priority lru.Priority) {
+	if c.counts[priority]--; c.counts[priority] == 0 {
+		delete(c.counts, priority)
+	}
+}
+
+// Remove 删除 key 对应的缓存项（包括被固定的），返回 key 是否存在
+func (c *Cache) Remove(key string) bool {
+	return c.RemoveWithReason(key, lru.ReasonDeleted)
+}
+
+// RemoveWithReason 与 Remove 相同，但以 reason 触发 OnEvicted。被删除的项不会进入 ghost 列表
+func (c *Cache) RemoveWithReason(key string, reason lru.EvictReason) bool {
+	element, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	delete(c.pinned, key)
+	c.removeElement(element, reason)
+	return true
+}
+
+// Clear 删除全部缓存项与 ghost，每一项都会以 ReasonCleared 触发 OnEvicted
+func (c *Cache) Clear() {
+	for _, l := range []*list.List{c.t1, c.t2} {
+		for element := l.Back(); element != nil; element = l.Back() {
+			c.removeElement(element, lru.ReasonCleared)
+		}
+	}
+	c.b1.Init()
+	c.b2.Init()
+	c.b1Bytes, c.b2Bytes = 0, 0
+	c.ghosts = make(map[string]*list.Element)
+	c.pinned = nil
+	c.p = 0
+}
+
+// Pin 固定已缓存的 key，使其不会被容量淘汰。
+// 若固定后被固定条目的总大小超过容量上限则返回错误，避免淘汰无法进行。
+func (c *Cache) Pin(key string) error {
+	element, ok := c.items[key]
+	if !ok {
+		return fmt.Errorf("arc: pin %q: key not cached", key)
+	}
+	if _, ok := c.pinned[key]; ok {
+		return nil
+	}
+	var pinned int64
+	for k := range c.pinned {
+		pinned += c.items[k].Value.(*entry).size()
+	}
+	if c.maxBytes != 0 && pinned+element.Value.(*entry).size() > c.maxBytes {
+		return fmt.Errorf("arc: pin %q: pinned entries would exceed capacity %d bytes", key, c.maxBytes)
+	}
+	if c.pinned == nil {
+		c.pinned = make(map[string]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	return nil
+}
+
+// Unpin 取消固定 key，之后它会重新参与淘汰
+func (c *Cache) Unpin(key string) {
+	delete(c.pinned, key)
+	c.shrink(false)
+}
+
+func (c *Cache) Len() int {
+	return len(c.items)
+}
+
+// Bytes 返回当前所有常驻项的 key 与 value 总字节数，不含 ghost
+func (c *Cache) Bytes() int64 {
+	return c.t1Bytes + c.t2Bytes
+}
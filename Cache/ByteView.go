@@ -1,7 +1,22 @@
 package cache
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+)
+
+var _ io.WriterTo = ByteView{}
+
 type ByteView struct{
-	bt []byte
+	bt     []byte
+	meta   Meta
+	expire time.Time // 过期时间，零值表示永不过期
+
+	// encoding 非空时 bt 保存的是按该编码压缩后的数据，sum 是原始值的校验和，见 NewGzipByteView
+	encoding string
+	sum      string
 }
 
 func NewByteView(b []byte) ByteView {
@@ -14,16 +29,116 @@ func cloneBytes(b []byte) []byte {
 	return c
 }
 
+// Len 返回值在内存中占用的字节数，压缩存储时为压缩后的大小
 func (b ByteView) Len() int {
 	return len(b.bt)
 }
 
+// ByteSlice 返回值的一份完整副本。大量调用方共享同一个大值时各自复制会使内存成倍增长，
+// 只需读取时应使用 Reader，多个 Reader 共享同一份底层字节。
 func (b ByteView) ByteSlice() []byte {
+	if b.encoding != "" {
+		return b.decode()
+	}
 	c := make([]byte,b.Len())
 	copy(c,b.bt)
 	return c
 }
 
 func (b ByteView) String() string {
+	if b.encoding != "" {
+		return string(b.decode())
+	}
 	return string(b.bt)
 }
+
+// Reader 返回读取底层字节的 io.Reader，不会复制数据，也无法通过它修改缓存值。
+// 压缩存储时返回解压后的数据。
+func (b ByteView) Reader() io.Reader {
+	if b.encoding != "" {
+		return bytes.NewReader(b.decode())
+	}
+	return bytes.NewReader(b.bt)
+}
+
+// WriteTo 将值写入 w，实现 io.WriterTo，未压缩存储时直接写出底层字节而不复制。
+// io.Writer 约定不得修改传入的切片，因此调用方无法借此修改缓存值。
+func (b ByteView) WriteTo(w io.Writer) (int64, error) {
+	data := b.bt
+	if b.encoding != "" {
+		data = b.decode()
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// Slice 返回值中 [start, end) 区间的副本，元数据保持不变。压缩存储时按解压后的值计算区间。
+// 区间超出范围时返回错误。
+func (b ByteView) Slice(start, end int) (ByteView, error) {
+	data := b.bt
+	if b.encoding != "" {
+		data = b.decode()
+	}
+	if start < 0 || end > len(data) || start > end {
+		return ByteView{}, fmt.Errorf("cache: slice [%d:%d] out of range with length %d", start, end, len(data))
+	}
+	return ByteView{bt: cloneBytes(data[start:end]), meta: b.meta}, nil
+}
+
+// SliceFrom 返回值从 start 开始到末尾的副本，start 超出范围时 panic
+func (b ByteView) SliceFrom(start int) ByteView {
+	data := b.bt
+	if b.encoding != "" {
+		data = b.decode()
+	}
+	v, err := b.Slice(start, len(data))
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// Equal 判断两个值的内容是否相同，压缩存储的值按解压后的内容比较，不比较元数据与过期时间
+func (b ByteView) Equal(other ByteView) bool {
+	x, y := b.bt, other.bt
+	if b.encoding != "" {
+		x = b.decode()
+	}
+	if other.encoding != "" {
+		y = other.decode()
+	}
+	return bytes.Equal(x, y)
+}
+
+// Clone 返回不与 b 共享底层字节的副本，元数据、过期时间与存储编码保持不变
+func (b ByteView) Clone() ByteView {
+	b.bt = cloneBytes(b.bt)
+	return b
+}
+
+// ExpiresAt 返回值的过期时间，零值表示永不过期
+func (b ByteView) ExpiresAt() time.Time {
+	return b.expire
+}
+
+func (b ByteView) expired(now time.Time) bool {
+	return !b.expire.IsZero() && !now.Before(b.expire)
+}
+
+// Meta 是缓存值的元数据，随 ByteView 一起保存并在节点间传递
+type Meta struct {
+	CreatedAt time.Time // 值被加载或写入的时间
+	Node      string    // 加载该值的节点地址，本地加载的值为空
+	Version   int64     // 加载该值的 Group 为其分配的递增版本号
+}
+
+// Meta 返回值的元数据
+func (b ByteView) Meta() Meta {
+	return b.meta
+}
+
+// WithMeta 返回共享同一份底层字节、元数据为 m 的 ByteView
+func (b ByteView) WithMeta(m Meta) ByteView {
+	b.meta = m
+	return b
+}
@@ -0,0 +1,20 @@
+package cache
+
+// Cacher 是 Group 所依赖的本地缓存接口，可用外部存储（如 Redis）替换默认的 LRU 实现
+type Cacher interface {
+	Get(key string) (ByteView, bool)
+	Add(key string, value ByteView)
+}
+
+var _ Cacher = (*Cache)(nil)
+
+// StaleReader 由能读出已过期但尚未清理的条目的缓存实现，供 Group 在回源失败时返回旧值
+type StaleReader interface {
+	PeekStale(key string) (ByteView, bool)
+}
+
+// Expirer 由支持过期时间的缓存实现，RemoveExpired 删除所有已过期的条目并返回删除数。
+// group 包的共享清理 goroutine 会定期调用它。
+type Expirer interface {
+	RemoveExpired() int
+}
@@ -1,34 +1,429 @@
 package cache
 
 import (
+	"fmt"
 	lru "geecache/LRU"
 	"sync"
-
+	"sync/atomic"
+	"time"
 )
 
+// Cache 是并发安全的缓存，默认按 LRU 淘汰，见 Policy。零值可直接使用，表示不限容量；
+// 零值的底层 lru 在首次写入时才创建，此前的读取、删除、清空等操作都按空缓存处理。
+// 需要淘汰回调时使用 NewCache，它会立即创建底层 lru。
 type Cache struct {
-	lru_cache *lru.Cache
+	lru_cache store // 按 Policy 创建，默认为 lru.Cache
 	mu        sync.RWMutex
 	Cache_bytes int64
+
+	// Policy 是淘汰策略，默认 PolicyLRU；需在第一次写入前设置
+	Policy Policy
+
+	// AdmitFraction 大于 0 时，大小超过 Cache_bytes*AdmitFraction 的条目不会被缓存，
+	// 避免单个大值把大量小条目挤出缓存。Cache_bytes 为 0（不限容量）时不生效。
+	AdmitFraction float64
+
+	// MaxEntries 大于 0 时限制条目数，写入后条目数超出时按淘汰策略淘汰条目，直到条目数与字节数都不超限；
+	// 可避免大量极小的值使元数据无限增长。0 表示不限制条目数
+	MaxEntries int
+
+	// Overflow 决定缓存已满时如何处理新写入，默认 EvictLRU
+	Overflow OverflowPolicy
+
+	// KeepExpired 为 true 时，Get 遇到过期条目只返回未命中而不删除，
+	// 使其仍可通过 PeekStale 读出，直到被 RemoveExpired 或容量淘汰清理
+	KeepExpired bool
+
+	// 访问统计使用原子计数，Stats 读取时无需与 Add/Get 竞争锁
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+
+	// OnEvicted 非 nil 时，条目因容量不足、过期、删除或清空离开缓存后被调用。
+	// 回调在释放锁之后执行，其中可以再调用该 Cache 的方法；并发写入时不同 goroutine 的回调可能交错。
+	OnEvicted func(key string, value ByteView)
+	// pending 是持有锁期间离开缓存、等待释放锁后回调的条目
+	pending []Entry
+}
+
+// NewCache 返回容量为 bytes 字节、按 LRU 淘汰的 Cache，0 表示不限容量，底层 lru 立即创建。
+// onEvicted 用作 OnEvicted，可以为 nil。
+func NewCache(bytes int64, onEvicted func(key string, value ByteView)) *Cache {
+	c := &Cache{Cache_bytes: bytes, OnEvicted: onEvicted}
+	c.lru_cache = c.newStore(c.onEvicted)
+	return c
+}
+
+// lazyInit 在首次写入时创建底层 lru，调用方需持有锁
+func (c *Cache) lazyInit() {
+	if c.lru_cache == nil {
+		c.lru_cache = c.newStore(c.onEvicted)
+	}
+}
+
+// OverflowPolicy 是缓存已满时的写入策略
+type OverflowPolicy int
+
+const (
+	EvictLRU  OverflowPolicy = iota // 淘汰最久未使用的条目腾出空间
+	RejectNew                       // 拒绝会超出容量的写入，保持现有条目不变
+)
+
+// CacheStats 是 Cache 的访问统计与当前用量
+type CacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64 // 因容量不足被淘汰的条目数
+	Bytes     int64 // 当前占用字节数
+	Entries   int   // 当前条目数
+}
+
+// Stats 返回当前的访问统计，可与 Add/Get 并发调用
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Bytes:     c.Bytes(),
+		Entries:   c.Len(),
+	}
+}
+
+// ResetStats 将访问计数清零，Bytes 与 Entries 反映当前用量，不受影响
+func (c *Cache) ResetStats() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.evictions.Store(0)
+}
+
+// onEvicted 在持有锁时由 lru 回调，Evictions 只统计容量淘汰
+func (c *Cache) onEvicted(key string, value lru.Value, reason lru.EvictReason) {
+	if reason == lru.ReasonCapacity {
+		c.evictions.Add(1)
+	}
+	if c.OnEvicted != nil {
+		c.pending = append(c.pending, Entry{Key: key, Value: value.(ByteView)})
+	}
+}
+
+// unlock 释放写锁，再执行持锁期间积累的 OnEvicted 回调，避免回调调用 Cache 时死锁
+func (c *Cache) unlock() {
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	for _, e := range pending {
+		c.OnEvicted(e.Key, e.Value)
+	}
+}
+
+// admit 判断条目是否允许进入缓存
+func (c *Cache) admit(key string, value ByteView) bool {
+	if c.AdmitFraction <= 0 || c.Cache_bytes == 0 {
+		return true
+	}
+	size := int64(len(key) + value.Len())
+	return float64(size) <= float64(c.Cache_bytes)*c.AdmitFraction
 }
 
 func (c *Cache)Add(key string, value ByteView)  {
+	c.TryAdd(key, value)
+}
+
+// AddWithTTL 写入 value 并在 ttl 后过期，ttl 不大于 0 时永不过期。
+// 过期条目不会再被 Get 返回，并在访问时惰性删除。
+func (c *Cache) AddWithTTL(key string, value ByteView, ttl time.Duration) bool {
+	value.expire = time.Time{}
+	if ttl > 0 {
+		value.expire = time.Now().Add(ttl)
+	}
+	return c.TryAdd(key, value)
+}
+
+// TryAdd 与 Add 相同，返回值是否被写入；未通过 AdmitFraction 或被 RejectNew 拒绝时返回 false
+func (c *Cache) TryAdd(key string, value ByteView) bool {
+	return c.add(key, value, nil)
+}
+
+// AddWithPriority 与 TryAdd 相同，但以指定的淘汰优先级写入：容量不足时先淘汰低优先级的条目，
+// 见 lru.Priority。Add 与 TryAdd 写入的新条目为 lru.PriorityNormal。
+func (c *Cache) AddWithPriority(key string, value ByteView, priority lru.Priority) bool {
+	return c.add(key, value, &priority)
+}
+
+// add 写入条目，priority 为 nil 时新条目使用默认优先级、已有条目保留原优先级
+func (c *Cache) add(key string, value ByteView, priority *lru.Priority) bool {
+	if !c.admit(key, value) {
+		return false
+	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	if c.lru_cache == nil{
-		c.lru_cache = lru.New(c.Cache_bytes,nil)
+	defer c.unlock()
+	c.lazyInit()
+	if !c.fits(key, value) {
+		return false
+	}
+	if priority != nil {
+		c.lru_cache.AddWithPriority(key, value, *priority)
+	} else {
+		c.lru_cache.Add(key, value)
+	}
+	c.trim()
+	return true
+}
+
+// trim 淘汰条目直到不超过 MaxEntries 或没有可淘汰的条目（都被固定），调用方需持有锁
+func (c *Cache) trim() {
+	c.trimStore(c.lru_cache)
+}
+
+// trimStore 对 s 执行 trim 的淘汰，ReplaceAll 也用它限制构建中的新内容
+func (c *Cache) trimStore(s store) {
+	for c.MaxEntries > 0 && s.Len() > c.MaxEntries {
+		n := s.Len()
+		s.RemoveOldest()
+		if s.Len() == n {
+			return
+		}
+	}
+}
+
+// fits 判断 RejectNew 策略下写入后是否仍不超过容量与 MaxEntries，调用方需持有锁
+func (c *Cache) fits(key string, value ByteView) bool {
+	if c.Overflow != RejectNew {
+		return true
+	}
+	size := c.lru_cache.Bytes() + int64(value.Len())
+	if old, ok := c.lru_cache.Peek(key); ok {
+		size -= int64(old.Len())
+	} else {
+		if c.MaxEntries > 0 && c.lru_cache.Len() >= c.MaxEntries {
+			return false
+		}
+		size += int64(len(key))
 	}
-	c.lru_cache.Add(key,value)
+	return c.Cache_bytes == 0 || size <= c.Cache_bytes
 }
 
 func (c *Cache)Get(key string)(ByteView,bool)  {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	// lru.Get 会调整链表顺序，必须持有写锁
+	c.mu.Lock()
+	defer c.unlock()
 	if c.lru_cache == nil{
+		c.misses.Add(1)
 		return ByteView{},false
 	}
 	if value,ok := c.lru_cache.Get(key);ok{
-		return value.(ByteView),true
+		if v := value.(ByteView); !v.expired(time.Now()) {
+			c.hits.Add(1)
+			return v,true
+		}
+		if !c.KeepExpired {
+			c.lru_cache.RemoveWithReason(key, lru.ReasonExpired)
+		}
 	}
+	c.misses.Add(1)
 	return ByteView{},false
-}
\ No newline at end of file
+}
+
+// RemoveExpired 删除所有已过期的条目，返回删除数，实现 Expirer
+func (c *Cache) RemoveExpired() int {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return 0
+	}
+	now := time.Now()
+	var keys []string
+	c.lru_cache.Range(func(key string, value lru.Value) bool {
+		if value.(ByteView).expired(now) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	for _, key := range keys {
+		c.lru_cache.RemoveWithReason(key, lru.ReasonExpired)
+	}
+	return len(keys)
+}
+
+// Peek 返回 key 对应的未过期值，不改变淘汰顺序，也不计入命中统计
+func (c *Cache) Peek(key string) (ByteView, bool) {
+	v, ok := c.PeekStale(key)
+	if !ok || v.expired(time.Now()) {
+		return ByteView{}, false
+	}
+	return v, true
+}
+
+// PeekStale 与 Peek 相同，但即使条目已过期也会返回，实现 StaleReader
+func (c *Cache) PeekStale(key string) (ByteView, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return ByteView{}, false
+	}
+	if value, ok := c.lru_cache.Peek(key); ok {
+		return value.(ByteView), true
+	}
+	return ByteView{}, false
+}
+
+// AddIfAbsent 仅当 key 不存在时写入 value，返回是否写入成功
+func (c *Cache) AddIfAbsent(key string, value ByteView) bool {
+	if !c.admit(key, value) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.unlock()
+	c.lazyInit()
+	if old, ok := c.lru_cache.Peek(key); ok && !old.(ByteView).expired(time.Now()) {
+		return false
+	}
+	if !c.fits(key, value) {
+		return false
+	}
+	c.lru_cache.Add(key, value)
+	c.trim()
+	return true
+}
+
+// Range 依次遍历未过期的缓存项，fn 返回 false 时停止遍历。顺序取决于淘汰策略：
+// PolicyLRU 下从最近使用到最久未使用；PolicyLFU 下按保留价值从高到低（即与淘汰顺序相反）；
+// PolicyARC 下先列出访问过至少两次的条目，再列出只访问过一次的条目，各自按最近使用排列。
+// 遍历期间持有锁，fn 中不能再调用 Cache 的方法。
+func (c *Cache) Range(fn func(key string, value ByteView) bool) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return
+	}
+	now := time.Now()
+	c.lru_cache.Range(func(key string, value lru.Value) bool {
+		if v := value.(ByteView); !v.expired(now) {
+			return fn(key, v)
+		}
+		return true
+	})
+}
+
+// ReplaceAll 用 entries 整体替换缓存内容。新内容在锁外构建好后在写锁内一次性替换，
+// 读者只会看到完整的旧内容或完整的新内容。超出容量或 MaxEntries 的部分按 map 遍历顺序被淘汰，
+// 不计入淘汰统计；旧条目（包括被固定的）与 Clear 一样以 lru.ReasonCleared 离开缓存并触发 OnEvicted。
+func (c *Cache) ReplaceAll(entries map[string]ByteView) {
+	// 构建期间的淘汰不计入统计，替换后才转给 onEvicted
+	var installed bool
+	next := c.newStore(func(key string, value lru.Value, reason lru.EvictReason) {
+		if installed {
+			c.onEvicted(key, value, reason)
+		}
+	})
+	for key, value := range entries {
+		if c.admit(key, value) {
+			next.Add(key, value)
+			c.trimStore(next)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+	// 旧条目的回调由 unlock 在释放锁后执行；没有回调时直接丢弃，免得持锁遍历旧内容
+	if c.lru_cache != nil && c.OnEvicted != nil {
+		c.lru_cache.Clear()
+	}
+	installed = true
+	c.lru_cache = next
+	c.trim()
+}
+
+// Keys 返回全部未过期条目的 key，顺序与 Range 相同。不改变淘汰顺序
+func (c *Cache) Keys() []string {
+	var keys []string
+	c.Range(func(key string, _ ByteView) bool {
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// Entry 是一个缓存条目
+type Entry struct {
+	Key   string
+	Value ByteView
+}
+
+// EntriesByRecency 返回全部缓存条目，顺序与 Range 相同：只有 PolicyLRU 下严格按从最近使用到最久未使用排列，
+// PolicyLFU 与 PolicyARC 下越靠前的条目越晚被淘汰。可用于按热度优先的顺序预热替换节点。不改变淘汰顺序。
+func (c *Cache) EntriesByRecency() []Entry {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]Entry, 0, c.lru_cache.Len())
+	c.lru_cache.Range(func(key string, value lru.Value) bool {
+		if v := value.(ByteView); !v.expired(now) {
+			entries = append(entries, Entry{Key: key, Value: v})
+		}
+		return true
+	})
+	return entries
+}
+
+// Pin 固定已缓存的 key，使其在容量压力下也不会被淘汰。
+// key 未缓存或固定条目总大小将超过容量时返回错误。
+func (c *Cache) Pin(key string) error {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return fmt.Errorf("cache: pin %q: key not cached", key)
+	}
+	return c.lru_cache.Pin(key)
+}
+
+// Unpin 取消固定 key
+func (c *Cache) Unpin(key string) {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache != nil {
+		c.lru_cache.Unpin(key)
+	}
+}
+
+// Bytes 返回当前已使用的字节数，只持有读锁，可频繁调用
+func (c *Cache) Bytes() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lru_cache == nil {
+		return 0
+	}
+	return c.lru_cache.Bytes()
+}
+
+// Len 返回当前条目数（包括已过期但尚未清理的）
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lru_cache == nil {
+		return 0
+	}
+	return c.lru_cache.Len()
+}
+
+// Delete 删除 key，返回 key 是否存在
+func (c *Cache) Delete(key string) bool {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache == nil {
+		return false
+	}
+	return c.lru_cache.Remove(key)
+}
+
+// Clear 删除全部条目，保留容量等配置与访问统计
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.unlock()
+	if c.lru_cache != nil {
+		c.lru_cache.Clear()
+	}
+}
@@ -1,8 +1,11 @@
 package cache
 
 import (
+	"bytes"
 	"fmt"
+	lru "geecache/LRU"
 	"math/rand"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -23,6 +26,629 @@ func TestCache_BasicAddGet(t *testing.T) {
 	}
 }
 
+func TestCache_ZeroValue(t *testing.T) {
+	var c Cache
+	if _, ok := c.Get("k"); ok {
+		t.Fatal("zero value cache should miss")
+	}
+	if c.Len() != 0 || c.Bytes() != 0 || c.Delete("k") {
+		t.Fatal("zero value cache should behave as empty")
+	}
+	c.Clear()
+
+	c.Add("k", ByteView{bt: []byte("v")})
+	if v, ok := c.Get("k"); !ok || v.String() != "v" {
+		t.Fatalf("zero value cache should be usable, got %v %v", v, ok)
+	}
+	if !c.Delete("k") || c.Len() != 0 {
+		t.Fatal("Delete should remove the entry")
+	}
+	c.Add("k", ByteView{bt: []byte("v")})
+	c.Clear()
+	if c.Len() != 0 || c.Bytes() != 0 {
+		t.Fatalf("Clear should empty the cache, got %d entries", c.Len())
+	}
+}
+
+func TestCache_AddIfAbsent(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+
+	if !c.AddIfAbsent("k1", ByteView{bt: []byte("first")}) {
+		t.Fatal("expected first AddIfAbsent to store the value")
+	}
+	if c.AddIfAbsent("k1", ByteView{bt: []byte("second")}) {
+		t.Fatal("expected second AddIfAbsent to be rejected")
+	}
+	if v, _ := c.Get("k1"); v.String() != "first" {
+		t.Fatalf("expected first, got %s", v.String())
+	}
+}
+
+func TestCache_AddIfAbsentConcurrent(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+	const numGoroutines = 100
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			if c.AddIfAbsent("race-key", ByteView{bt: []byte(fmt.Sprintf("v%d", id))}) {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if winners != 1 {
+		t.Fatalf("expected exactly one winner, got %d", winners)
+	}
+}
+
+func TestCache_Snapshot(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+	if c.Snapshot().Len() != 0 {
+		t.Fatal("expected empty snapshot before any Add")
+	}
+
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	s := c.Snapshot()
+
+	// 快照之后的写入不影响快照
+	c.Add("k3", ByteView{bt: []byte("v3")})
+	c.Add("k1", ByteView{bt: []byte("changed")})
+
+	got := make(map[string]string)
+	s.Range(func(key string, value ByteView) bool {
+		got[key] = value.String()
+		return true
+	})
+	if len(got) != 2 || got["k1"] != "v1" || got["k2"] != "v2" {
+		t.Fatalf("unexpected snapshot contents %v", got)
+	}
+}
+
+func TestCache_EntriesByRecency(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	c.Add("k3", ByteView{bt: []byte("v3")})
+	c.Get("k1")
+
+	var keys []string
+	for _, e := range c.EntriesByRecency() {
+		keys = append(keys, e.Key)
+	}
+	if fmt.Sprint(keys) != "[k1 k3 k2]" {
+		t.Fatalf("expected MRU-first order [k1 k3 k2], got %v", keys)
+	}
+}
+
+func TestCache_ReplaceAll(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+	c.Add("old", ByteView{bt: []byte("v")})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// 并发读者只能看到完整的旧集合或完整的新集合；Keys 在一次加锁内取得快照
+		for i := 0; i < 1000; i++ {
+			keys := c.Keys()
+			sort.Strings(keys)
+			if s := fmt.Sprint(keys); s != "[old]" && s != "[a b]" {
+				t.Errorf("observed a partial replacement: %v", keys)
+				return
+			}
+		}
+	}()
+	c.ReplaceAll(map[string]ByteView{
+		"a": {bt: []byte("1")},
+		"b": {bt: []byte("2")},
+	})
+	wg.Wait()
+
+	if _, ok := c.Get("old"); ok {
+		t.Fatal("old entries should be gone")
+	}
+	if v, ok := c.Get("b"); !ok || v.String() != "2" {
+		t.Fatalf("expected b=2, got %v %v", v, ok)
+	}
+}
+
+func TestCache_ReplaceAllEvicts(t *testing.T) {
+	var evicted []string
+	c := NewCache(1024, func(key string, value ByteView) {
+		evicted = append(evicted, key)
+	})
+	c.MaxEntries = 2
+	c.Add("old1", ByteView{bt: []byte("v")})
+	c.Add("old2", ByteView{bt: []byte("v")})
+
+	c.ReplaceAll(map[string]ByteView{
+		"a": {bt: []byte("1")},
+		"b": {bt: []byte("2")},
+		"c": {bt: []byte("3")},
+	})
+	sort.Strings(evicted)
+	if fmt.Sprint(evicted) != "[old1 old2]" {
+		t.Fatalf("replaced entries should trigger OnEvicted, got %v", evicted)
+	}
+	if c.Len() != 2 {
+		t.Fatalf("ReplaceAll should respect MaxEntries, got %d entries", c.Len())
+	}
+	if st := c.Stats(); st.Evictions != 0 {
+		t.Fatalf("replacement should not count as evictions, got %d", st.Evictions)
+	}
+}
+
+func TestCache_AddWithTTL(t *testing.T) {
+	c := &Cache{Cache_bytes: 1024}
+	c.AddWithTTL("short", ByteView{bt: []byte("v")}, 10*time.Millisecond)
+	c.AddWithTTL("forever", ByteView{bt: []byte("v")}, 0)
+
+	if _, ok := c.Get("short"); !ok {
+		t.Fatal("entry should be readable before it expires")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := c.Get("short"); ok {
+		t.Fatal("expired entry should not be returned")
+	}
+	if c.Bytes() != int64(len("forever")+1) {
+		t.Fatalf("expired entry should be removed on access, got %d bytes", c.Bytes())
+	}
+	if _, ok := c.Get("forever"); !ok {
+		t.Fatal("zero TTL should never expire")
+	}
+
+	c.AddWithTTL("reaped", ByteView{bt: []byte("v")}, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if n := c.RemoveExpired(); n != 1 {
+		t.Fatalf("expected 1 expired entry removed, got %d", n)
+	}
+}
+
+func TestCache_Pin(t *testing.T) {
+	c := &Cache{Cache_bytes: 64}
+	c.Add("config", ByteView{bt: []byte("important")})
+	if err := c.Pin("config"); err != nil {
+		t.Fatalf("unexpected pin error: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("k%d", i), ByteView{bt: []byte("filler")})
+	}
+	if _, ok := c.Get("config"); !ok {
+		t.Fatal("pinned key should survive eviction")
+	}
+
+	c.Unpin("config")
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("k%d", i), ByteView{bt: []byte("filler")})
+	}
+	if _, ok := c.Get("config"); ok {
+		t.Fatal("unpinned key should be evicted again")
+	}
+}
+
+func TestCache_PinOnlyPinnedLeft(t *testing.T) {
+	c := &Cache{Cache_bytes: 32}
+	c.Add("a", ByteView{bt: []byte("0123456789")})
+	c.Add("b", ByteView{bt: []byte("0123456789")})
+
+	if err := c.Pin("a"); err != nil {
+		t.Fatalf("unexpected pin error: %v", err)
+	}
+	if err := c.Pin("b"); err != nil {
+		t.Fatalf("unexpected pin error: %v", err)
+	}
+	if err := c.Pin("missing"); err == nil {
+		t.Fatal("expected error when pinning a missing key")
+	}
+
+	// 只剩固定条目时淘汰必须停止，而不是死循环
+	c.Add("a", ByteView{bt: make([]byte, 40)})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("pinned key a should remain")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Fatal("pinned key b should remain")
+	}
+}
+
+func TestCache_AdmitFraction(t *testing.T) {
+	c := &Cache{Cache_bytes: 100, AdmitFraction: 0.25}
+	for i := 0; i < 8; i++ {
+		c.Add(fmt.Sprintf("s%d", i), ByteView{bt: []byte("small")})
+	}
+
+	// 60 字节超过容量的 25%，不应被缓存，也不应挤掉小条目
+	c.Add("big", ByteView{bt: make([]byte, 60)})
+	if _, ok := c.Get("big"); ok {
+		t.Fatal("oversized value should not be admitted")
+	}
+	for i := 0; i < 8; i++ {
+		if _, ok := c.Get(fmt.Sprintf("s%d", i)); !ok {
+			t.Fatalf("small entry s%d should be retained", i)
+		}
+	}
+
+	// 关闭准入限制后大值会挤掉小条目
+	c.AdmitFraction = 0
+	c.Add("big", ByteView{bt: make([]byte, 60)})
+	if _, ok := c.Get("big"); !ok {
+		t.Fatal("value should be admitted when AdmitFraction is 0")
+	}
+}
+
+func TestCache_RejectNew(t *testing.T) {
+	c := &Cache{Cache_bytes: 8, Overflow: RejectNew}
+	if !c.TryAdd("k1", ByteView{bt: []byte("v1")}) || !c.TryAdd("k2", ByteView{bt: []byte("v2")}) {
+		t.Fatal("entries within capacity should be stored")
+	}
+	if c.TryAdd("k3", ByteView{bt: []byte("v3")}) {
+		t.Fatal("new entry exceeding capacity should be rejected")
+	}
+	if _, ok := c.Get("k1"); !ok {
+		t.Fatal("existing entries should not be evicted")
+	}
+	if !c.TryAdd("k1", ByteView{bt: []byte("x1")}) {
+		t.Fatal("same-size update should fit")
+	}
+	if s := c.Stats(); s.Evictions != 0 {
+		t.Fatalf("RejectNew should never evict, got %+v", s)
+	}
+}
+
+func TestCache_MaxEntries(t *testing.T) {
+	c := &Cache{MaxEntries: 2}
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	c.Get("k1")
+	c.Add("k3", ByteView{bt: []byte("v3")})
+	if _, ok := c.Get("k2"); ok || c.Len() != 2 {
+		t.Fatalf("least recently used entry should be evicted, len %d", c.Len())
+	}
+	if s := c.Stats(); s.Evictions != 1 {
+		t.Fatalf("entry limit evictions should be counted, got %+v", s)
+	}
+
+	// 两个限制同时生效
+	c = &Cache{Cache_bytes: 8, MaxEntries: 3}
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	c.Add("k3", ByteView{bt: []byte("v3")})
+	if c.Len() != 2 || c.Bytes() > 8 {
+		t.Fatalf("byte limit should still apply, got %d entries %d bytes", c.Len(), c.Bytes())
+	}
+
+	// 被固定的条目不会被淘汰
+	c = &Cache{MaxEntries: 1}
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Pin("k1")
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	if _, ok := c.Get("k1"); !ok {
+		t.Fatal("pinned entry should survive the entry limit")
+	}
+
+	c = &Cache{MaxEntries: 1, Overflow: RejectNew}
+	if !c.TryAdd("k1", ByteView{bt: []byte("v1")}) || c.TryAdd("k2", ByteView{bt: []byte("v2")}) {
+		t.Fatal("RejectNew should reject new keys over the entry limit")
+	}
+	if !c.TryAdd("k1", ByteView{bt: []byte("x1")}) {
+		t.Fatal("updating an existing key should fit")
+	}
+}
+
+func TestCache_Keys(t *testing.T) {
+	c := &Cache{}
+	if keys := c.Keys(); len(keys) != 0 {
+		t.Fatalf("empty cache should have no keys, got %v", keys)
+	}
+	c.Add("k1", ByteView{bt: []byte("v1")})
+	c.Add("k2", ByteView{bt: []byte("v2")})
+	c.AddWithTTL("expired", ByteView{bt: []byte("v")}, time.Nanosecond)
+	c.Get("k1")
+	time.Sleep(time.Millisecond)
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != "k1" || keys[1] != "k2" {
+		t.Fatalf("expected [k1 k2] without expired keys, got %v", keys)
+	}
+
+	l := lru.New(0, nil)
+	l.Add("a", ByteView{bt: []byte("1")})
+	l.Add("b", ByteView{bt: []byte("2")})
+	l.Get("a")
+	if keys := l.Keys(); len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Fatalf("lru keys should be ordered by recency, got %v", keys)
+	}
+}
+
+func TestCache_Stats(t *testing.T) {
+	c := &Cache{Cache_bytes: 16}
+	c.Get("k1")
+	c.Add("k1", ByteView{bt: []byte("0123456")})
+	c.Get("k1")
+	c.Add("k2", ByteView{bt: []byte("0123456")})
+
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 1 || s.Evictions != 1 || s.Entries != 1 || s.Bytes != 9 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+
+	c.ResetStats()
+	if s := c.Stats(); s != (CacheStats{Bytes: 9, Entries: 1}) {
+		t.Fatalf("expected zeroed counters, got %+v", s)
+	}
+}
+
+func TestCache_StatsConcurrent(t *testing.T) {
+	c := NewCache(1<<10, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("k%d", (i*200+j)%50)
+				c.Add(key, NewByteView([]byte("value")))
+				c.Get(key)
+				c.Stats()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s := c.Stats(); s.Hits+s.Misses != 8*200 {
+		t.Fatalf("expected %d lookups, got %+v", 8*200, s)
+	}
+}
+
+func TestCache_Priority(t *testing.T) {
+	// 每个条目 2+8=10 字节，容量可容纳 4 个
+	c := NewCache(40, nil)
+	c.AddWithPriority("h1", NewByteView([]byte("high-one")), lru.PriorityHigh)
+	c.AddWithPriority("h2", NewByteView([]byte("high-two")), lru.PriorityHigh)
+	c.Add("n1", NewByteView([]byte("normal-1")))
+	c.AddWithPriority("l1", NewByteView([]byte("low-one!")), lru.PriorityLow)
+
+	// 高优先级条目最久未使用，但压力下先淘汰低优先级，再淘汰普通优先级
+	c.Add("n2", NewByteView([]byte("normal-2")))
+	if _, ok := c.Peek("l1"); ok {
+		t.Fatal("low priority entry should be evicted first")
+	}
+	c.Add("n3", NewByteView([]byte("normal-3")))
+	if _, ok := c.Peek("n1"); ok {
+		t.Fatal("oldest normal entry should be evicted before high priority ones")
+	}
+	for i := 0; i < 10; i++ {
+		c.Add(fmt.Sprintf("x%d", i), NewByteView([]byte("normal-x")))
+	}
+	for _, key := range []string{"h1", "h2"} {
+		if _, ok := c.Peek(key); !ok {
+			t.Fatalf("high priority entry %s should outlive normal ones", key)
+		}
+	}
+
+	// Add 更新已有条目时保留其优先级
+	c.Add("h1", NewByteView([]byte("high-new")))
+	for i := 0; i < 10; i++ {
+		c.Add(fmt.Sprintf("y%d", i), NewByteView([]byte("normal-y")))
+	}
+	if v, ok := c.Peek("h1"); !ok || v.String() != "high-new" {
+		t.Fatal("updated high priority entry should keep its priority")
+	}
+
+	// 只剩高优先级条目时按 LRU 淘汰
+	d := NewCache(20, nil)
+	d.AddWithPriority("a1", NewByteView([]byte("aaaaaaaa")), lru.PriorityHigh)
+	d.AddWithPriority("a2", NewByteView([]byte("aaaaaaaa")), lru.PriorityHigh)
+	d.AddWithPriority("a3", NewByteView([]byte("aaaaaaaa")), lru.PriorityHigh)
+	if _, ok := d.Peek("a1"); ok || d.Len() != 2 {
+		t.Fatal("high priority entries should be evicted in LRU order among themselves")
+	}
+}
+
+func TestCache_PolicyLFU(t *testing.T) {
+	// 每个条目 4+4=8 字节，容量可容纳 4 个
+	for _, tc := range []struct {
+		policy  Policy
+		hotKept bool
+	}{
+		{PolicyLRU, false},
+		{PolicyLFU, true},
+		{PolicyARC, true},
+	} {
+		c := &Cache{Cache_bytes: 32, Policy: tc.policy}
+		c.Add("hot1", NewByteView([]byte("vvvv")))
+		c.Add("hot2", NewByteView([]byte("vvvv")))
+		for i := 0; i < 5; i++ {
+			c.Get("hot1")
+			c.Get("hot2")
+		}
+		// 一次性扫描
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("s%03d", i)
+			c.Add(key, NewByteView([]byte("vvvv")))
+			c.Get(key)
+		}
+		_, ok1 := c.Peek("hot1")
+		_, ok2 := c.Peek("hot2")
+		if ok1 != tc.hotKept || ok2 != tc.hotKept {
+			t.Errorf("policy %d: expected hot keys kept=%v, got %v %v", tc.policy, tc.hotKept, ok1, ok2)
+		}
+		if c.Len() != 4 || c.Bytes() != 32 {
+			t.Errorf("policy %d: expected 4 entries / 32 bytes, got %d / %d", tc.policy, c.Len(), c.Bytes())
+		}
+	}
+
+	// LFU 同样支持固定、删除与淘汰回调
+	c := &Cache{Cache_bytes: 16, Policy: PolicyLFU}
+	c.Add("pin1", NewByteView([]byte("vvvv")))
+	if err := c.Pin("pin1"); err != nil {
+		t.Fatal(err)
+	}
+	c.Add("aaaa", NewByteView([]byte("vvvv")))
+	c.Get("aaaa")
+	c.Add("bbbb", NewByteView([]byte("vvvv")))
+	if _, ok := c.Peek("pin1"); !ok {
+		t.Fatal("pinned key should not be evicted under LFU")
+	}
+	if !c.Delete("aaaa") || c.Len() != 1 {
+		t.Fatalf("Delete should remove the entry, got %d entries", c.Len())
+	}
+	if s := c.Stats(); s.Evictions != 1 {
+		t.Fatalf("expected 1 eviction, got %+v", s)
+	}
+}
+
+func TestCache_PolicyARC(t *testing.T) {
+	// 每个条目 4+4=8 字节，容量可容纳 4 个
+	for _, tc := range []struct {
+		policy Policy
+		kept   bool
+	}{
+		{PolicyLRU, false},
+		{PolicyARC, true},
+	} {
+		c := &Cache{Cache_bytes: 32, Policy: tc.policy}
+		c.Add("aaaa", NewByteView([]byte("vvvv")))
+		c.Add("bbbb", NewByteView([]byte("vvvv")))
+		c.Get("aaaa")
+		c.Get("bbbb")
+		for _, key := range []string{"cccc", "dddd", "eeee"} {
+			c.Add(key, NewByteView([]byte("vvvv")))
+		}
+		// cccc 刚被淘汰，ARC 中它的 ghost 命中后直接进入 T2，之后的扫描不会再淘汰它
+		c.Add("cccc", NewByteView([]byte("vvvv")))
+		for i := 0; i < 10; i++ {
+			c.Add(fmt.Sprintf("s%03d", i), NewByteView([]byte("vvvv")))
+		}
+		if _, ok := c.Peek("cccc"); ok != tc.kept {
+			t.Errorf("policy %d: expected re-added key kept=%v, got %v", tc.policy, tc.kept, ok)
+		}
+		if c.Len() != 4 || c.Bytes() != 32 {
+			t.Errorf("policy %d: expected 4 entries / 32 bytes, got %d / %d", tc.policy, c.Len(), c.Bytes())
+		}
+	}
+
+	// ARC 同样支持固定、优先级、删除与淘汰回调
+	c := &Cache{Cache_bytes: 16, Policy: PolicyARC}
+	c.Add("pin1", NewByteView([]byte("vvvv")))
+	if err := c.Pin("pin1"); err != nil {
+		t.Fatal(err)
+	}
+	c.AddWithPriority("low1", NewByteView([]byte("vvvv")), lru.PriorityLow)
+	c.Get("low1")
+	c.Add("aaaa", NewByteView([]byte("vvvv")))
+	if _, ok := c.Peek("pin1"); !ok {
+		t.Fatal("pinned key should not be evicted under ARC")
+	}
+	if _, ok := c.Peek("low1"); ok {
+		t.Fatal("low priority key should be evicted first under ARC")
+	}
+	if !c.Delete("aaaa") || c.Len() != 1 {
+		t.Fatalf("Delete should remove the entry, got %d entries", c.Len())
+	}
+	if s := c.Stats(); s.Evictions != 1 {
+		t.Fatalf("expected 1 eviction, got %+v", s)
+	}
+}
+
+func TestByteView_WriteTo(t *testing.T) {
+	bv := NewByteView([]byte("hello"))
+	var buf bytes.Buffer
+	if n, err := bv.WriteTo(&buf); err != nil || n != 5 || buf.String() != "hello" {
+		t.Fatalf("WriteTo = %d, %v, %q", n, err, buf.String())
+	}
+	buf.Bytes()[0] = 'x'
+	if bv.String() != "hello" {
+		t.Fatalf("mutating written bytes changed the view: %q", bv.String())
+	}
+
+	buf.Reset()
+	gz := NewGzipByteView(bytes.Repeat([]byte("a"), 100))
+	if n, err := gz.WriteTo(&buf); err != nil || n != 100 || buf.String() != string(bytes.Repeat([]byte("a"), 100)) {
+		t.Fatalf("gzip WriteTo = %d, %v", n, err)
+	}
+}
+
+func TestByteView_Slice(t *testing.T) {
+	bv := NewByteView([]byte("0123456789")).WithMeta(Meta{Version: 3})
+	part, err := bv.Slice(2, 5)
+	if err != nil || part.String() != "234" || part.Meta().Version != 3 {
+		t.Fatalf("Slice(2, 5) = %q, %v", part.String(), err)
+	}
+	for _, r := range [][2]int{{-1, 2}, {5, 4}, {0, 11}} {
+		if _, err := bv.Slice(r[0], r[1]); err == nil {
+			t.Errorf("Slice(%d, %d) should fail", r[0], r[1])
+		}
+	}
+	if got := bv.SliceFrom(7).String(); got != "789" {
+		t.Fatalf("SliceFrom(7) = %q", got)
+	}
+	if got := bv.SliceFrom(10).Len(); got != 0 {
+		t.Fatalf("SliceFrom(Len) should be empty, got %d bytes", got)
+	}
+
+	// 压缩存储的值按解压后的内容切片
+	gz := NewGzipByteView([]byte("0123456789"))
+	if part, err := gz.Slice(8, 10); err != nil || part.String() != "89" || part.Encoding() != "" {
+		t.Fatalf("gzip Slice(8, 10) = %q, %v", part.String(), err)
+	}
+}
+
+func TestByteView_EqualClone(t *testing.T) {
+	// 无效 UTF-8 的二进制值也能正确比较
+	a := NewByteView([]byte{0xff, 0xfe, 0x00})
+	if !a.Equal(NewByteView([]byte{0xff, 0xfe, 0x00}).WithMeta(Meta{Version: 1})) {
+		t.Fatal("values with the same bytes should be equal regardless of meta")
+	}
+	if a.Equal(NewByteView([]byte{0xff, 0xfd, 0x00})) || a.Equal(ByteView{}) {
+		t.Fatal("values with different bytes should not be equal")
+	}
+	if !NewGzipByteView([]byte("0123456789")).Equal(NewByteView([]byte("0123456789"))) {
+		t.Fatal("gzip values should compare by decoded content")
+	}
+
+	v := NewByteView([]byte("value")).WithMeta(Meta{Version: 2})
+	c := v.Clone()
+	c.bt[0] = 'V'
+	if v.String() != "value" || c.String() != "Value" || c.Meta().Version != 2 {
+		t.Fatalf("clone should not share bytes, got %q and %q", v.String(), c.String())
+	}
+}
+
+func TestCache_EvictReason(t *testing.T) {
+	reasons := make(map[string]lru.EvictReason)
+	l := lru.New(int64(len("k1")+2+len("k2")+2), func(key string, value lru.Value, reason lru.EvictReason) {
+		reasons[key] = reason
+	})
+	l.Add("k1", ByteView{bt: []byte("v1")})
+	l.Add("k2", ByteView{bt: []byte("v2")})
+	l.Add("k3", ByteView{bt: []byte("v3")})
+	l.Remove("k2")
+	l.Clear()
+
+	want := map[string]lru.EvictReason{
+		"k1": lru.ReasonCapacity,
+		"k2": lru.ReasonDeleted,
+		"k3": lru.ReasonCleared,
+	}
+	for key, reason := range want {
+		if reasons[key] != reason {
+			t.Errorf("%s: expected reason %v, got %v", key, reason, reasons[key])
+		}
+	}
+	if l.Len() != 0 || l.Bytes() != 0 {
+		t.Fatalf("expected empty cache, got %d entries %d bytes", l.Len(), l.Bytes())
+	}
+}
+
 // ---------- 并发读写测试 ----------
 
 func TestCache_ConcurrentReadWrite(t *testing.T) {
@@ -164,13 +790,11 @@ func TestCache_EvictionCallbackConcurrency(t *testing.T) {
 	var mu sync.Mutex
 	evictedKeys := make([]string, 0)
 
-	// 用底层 lru 直接测试回调
-	c := &Cache{Cache_bytes: 64}
-
-	// 手动初始化以注入回调（绕过懒加载）
-	c.mu.Lock()
-	c.lru_cache = nil // 重置
-	c.mu.Unlock()
+	c := NewCache(64, func(key string, value ByteView) {
+		mu.Lock()
+		evictedKeys = append(evictedKeys, key)
+		mu.Unlock()
+	})
 
 	const numGoroutines = 30
 	const numOps = 100
@@ -191,8 +815,48 @@ func TestCache_EvictionCallbackConcurrency(t *testing.T) {
 	wg.Wait()
 
 	mu.Lock()
-	t.Logf("evicted %d keys during concurrent adds", len(evictedKeys))
-	mu.Unlock()
+	defer mu.Unlock()
+	// 写入的 key 互不相同，每个 key 要么仍在缓存中，要么触发过一次回调
+	if len(evictedKeys) == 0 || len(evictedKeys)+c.Len() != numGoroutines*numOps {
+		t.Fatalf("expected %d evicted + remaining, got %d + %d", numGoroutines*numOps, len(evictedKeys), c.Len())
+	}
+}
+
+func TestCache_OnEvictedReentrant(t *testing.T) {
+	c := &Cache{Cache_bytes: 8}
+	var evicted []string
+	c.OnEvicted = func(key string, value ByteView) {
+		// 回调在释放锁后执行，可以再访问缓存
+		if _, ok := c.Get(key); ok {
+			t.Errorf("%s should be gone when its callback runs", key)
+		}
+		evicted = append(evicted, key)
+	}
+	c.Add("k1", NewByteView([]byte("v1")))
+	c.Add("k2", NewByteView([]byte("v2")))
+	c.Add("k3", NewByteView([]byte("v3")))
+	c.Clear()
+	if len(evicted) != 3 || evicted[0] != "k1" {
+		t.Fatalf("expected k1 evicted then the rest cleared, got %v", evicted)
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	evicted := make(map[string]string)
+	c := NewCache(8, func(key string, value ByteView) {
+		evicted[key] = value.String()
+	})
+	// 立即创建底层 lru，无需等到第一次写入
+	if c.lru_cache == nil {
+		t.Fatal("NewCache should initialize the store eagerly")
+	}
+	c.Add("k1", NewByteView([]byte("v1")))
+	c.Add("k2", NewByteView([]byte("v2")))
+	c.Add("k3", NewByteView([]byte("v3")))
+	c.Delete("k3")
+	if len(evicted) != 2 || evicted["k1"] != "v1" || evicted["k3"] != "v3" {
+		t.Fatalf("expected k1 evicted and k3 deleted, got %v", evicted)
+	}
 }
 
 // ---------- Benchmark：并发读写吞吐 ----------
@@ -214,3 +878,47 @@ func BenchmarkCache_ConcurrentOps(b *testing.B) {
 		}
 	})
 }
+
+// ---------- Benchmark：扫描负载下的命中率 ----------
+
+// BenchmarkCache_ScanHitRatio 在热点访问中穿插一次性扫描，比较各淘汰策略的命中率（hit-ratio 指标）。
+// 200 个热点 key 按近似 Zipf 分布访问，每 1000 次访问后扫描 500 个从未出现过的 key，缓存可容纳 250 个条目
+func BenchmarkCache_ScanHitRatio(b *testing.B) {
+	for _, policy := range []struct {
+		name   string
+		policy Policy
+	}{
+		{"LRU", PolicyLRU},
+		{"LFU", PolicyLFU},
+		{"ARC", PolicyARC},
+	} {
+		b.Run(policy.name, func(b *testing.B) {
+			value := NewByteView([]byte("vvvv"))
+			c := &Cache{Cache_bytes: 250 * int64(len("hot-000")+value.Len()), Policy: policy.policy}
+			r := rand.New(rand.NewSource(1))
+			zipf := rand.NewZipf(r, 1.1, 1, 199)
+			scan := 0
+			hits := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				key := fmt.Sprintf("hot-%03d", zipf.Uint64())
+				if _, ok := c.Get(key); ok {
+					hits++
+				} else {
+					c.Add(key, value)
+				}
+				if i%1000 == 999 {
+					for j := 0; j < 500; j++ {
+						key := fmt.Sprintf("s%06d", scan%1000000)
+						if _, ok := c.Get(key); !ok {
+							c.Add(key, value)
+						}
+						scan++
+					}
+				}
+			}
+			// 扫描的 key 不会命中，只统计热点访问的命中率
+			b.ReportMetric(float64(hits)/float64(b.N), "hit-ratio")
+		})
+	}
+}
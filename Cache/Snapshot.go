@@ -0,0 +1,55 @@
+package cache
+
+import (
+	lru "geecache/LRU"
+	"time"
+)
+
+// Snapshot 是 Cache 在某一时刻的只读副本
+//
+// 创建快照时会在锁内复制全部 key 与 ByteView，耗时与条目数成正比；
+// 由于 ByteView 只读，快照与缓存共享 value 的底层字节，
+// 额外内存约为 key 的大小加每个条目的少量元数据，而不是整个缓存的大小。
+// 快照不会反映创建之后的任何写入或淘汰。
+type Snapshot struct {
+	entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	key   string
+	value ByteView
+}
+
+// Snapshot 返回当前缓存内容的只读副本，条目顺序与 Range 相同：PolicyLRU 下从最近使用到最久未使用，
+// PolicyLFU 与 PolicyARC 下见 Range
+func (c *Cache) Snapshot() *Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &Snapshot{}
+	if c.lru_cache == nil {
+		return s
+	}
+	now := time.Now()
+	s.entries = make([]snapshotEntry, 0, c.lru_cache.Len())
+	c.lru_cache.Range(func(key string, value lru.Value) bool {
+		if v := value.(ByteView); !v.expired(now) {
+			s.entries = append(s.entries, snapshotEntry{key, v})
+		}
+		return true
+	})
+	return s
+}
+
+// Range 依次遍历快照中的条目，fn 返回 false 时停止遍历。不会阻塞缓存的读写。
+func (s *Snapshot) Range(fn func(key string, value ByteView) bool) {
+	for _, e := range s.entries {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Len 返回快照中的条目数
+func (s *Snapshot) Len() int {
+	return len(s.entries)
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// EncodingGzip 是 gzip 压缩存储的编码名，与 HTTP Content-Encoding 一致
+const EncodingGzip = "gzip"
+
+// NewGzipByteView 以 gzip 压缩的形式保存 b，Len 返回压缩后的大小，ByteSlice 等读取方法透明解压。
+// 同时记录原始值的 SHA-256 校验和，使 Serve 可以不解压就把压缩数据直接发给支持 gzip 的客户端。
+func NewGzipByteView(b []byte) ByteView {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(b)
+	zw.Close()
+	sum := sha256.Sum256(b)
+	return ByteView{bt: buf.Bytes(), encoding: EncodingGzip, sum: hex.EncodeToString(sum[:])}
+}
+
+// Encoding 返回值在内存中的压缩编码，未压缩时为空
+func (b ByteView) Encoding() string {
+	return b.encoding
+}
+
+// Encoded 返回读取压缩后数据的 io.Reader 与原始值的 SHA-256 校验和（十六进制），
+// 仅在 Encoding 非空时有意义
+func (b ByteView) Encoded() (io.Reader, string) {
+	return bytes.NewReader(b.bt), b.sum
+}
+
+// decode 返回解压后的原始值，数据由 NewGzipByteView 生成，解压失败说明内存中的数据已损坏
+func (b ByteView) decode() []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(b.bt))
+	if err != nil {
+		panic("cache: corrupt compressed value: " + err.Error())
+	}
+	plain, err := io.ReadAll(zr)
+	if err != nil {
+		panic("cache: corrupt compressed value: " + err.Error())
+	}
+	return plain
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	arc "geecache/ARC"
+	lfu "geecache/LFU"
+	lru "geecache/LRU"
+)
+
+// Policy 是 Cache 的淘汰策略
+type Policy int
+
+const (
+	PolicyLRU Policy = iota // 淘汰最久未使用的条目，默认策略
+	PolicyLFU               // 淘汰访问次数最少的条目，适合少量热点 key 加大量一次性扫描的访问模式
+	PolicyARC               // 自适应替换缓存，在最近性与频率之间自动平衡，抗扫描且能跟上热点变化，见 arc 包
+)
+
+// store 是淘汰策略的实现需要提供的操作，lru.Cache、lfu.Cache 与 arc.Cache 都实现了它
+type store interface {
+	Add(key string, value lru.Value)
+	AddWithPriority(key string, value lru.Value, priority lru.Priority)
+	Get(key string) (lru.Value, bool)
+	Peek(key string) (lru.Value, bool)
+	Range(fn func(key string, value lru.Value) bool)
+	Remove(key string) bool
+	RemoveWithReason(key string, reason lru.EvictReason) bool
+	RemoveOldest()
+	Clear()
+	Pin(key string) error
+	Unpin(key string)
+	Len() int
+	Bytes() int64
+}
+
+var (
+	_ store = (*lru.Cache)(nil)
+	_ store = (*lfu.Cache)(nil)
+	_ store = (*arc.Cache)(nil)
+)
+
+// newStore 按 Policy 创建底层存储
+func (c *Cache) newStore(onEvicted func(key string, value lru.Value, reason lru.EvictReason)) store {
+	switch c.Policy {
+	case PolicyLFU:
+		return lfu.New(c.Cache_bytes, onEvicted)
+	case PolicyARC:
+		return arc.New(c.Cache_bytes, onEvicted)
+	}
+	return lru.New(c.Cache_bytes, onEvicted)
+}
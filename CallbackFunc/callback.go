@@ -1,7 +1,28 @@
 package callbackfunc
 
+import "context"
+
 type CallbackFunc func(key string) ([]byte ,error)
 
 func (f CallbackFunc) Get(key string)([]byte ,error)  {
 	return f(key)
 }
+
+// WithContext 将 f 适配为忽略 ctx 的 CallbackFuncCtx，f 为 nil 时返回 nil
+func (f CallbackFunc) WithContext() CallbackFuncCtx {
+	if f == nil {
+		return nil
+	}
+	return func(_ context.Context, key string) ([]byte, error) {
+		return f(key)
+	}
+}
+
+// CallbackFuncCtx 是感知 context 的回调函数，ctx 来自 Group.GetContext，
+// 调用方放弃等待时回调可据此取消对数据源的查询
+type CallbackFuncCtx func(ctx context.Context, key string) ([]byte, error)
+
+// Get 以 context.Background() 调用 f
+func (f CallbackFuncCtx) Get(key string) ([]byte, error) {
+	return f(context.Background(), key)
+}
@@ -1,6 +1,7 @@
 package callbackfunc
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -152,6 +153,24 @@ func TestCallbackFunc_GetEqualsDirectCall(t *testing.T) {
 	}
 }
 
+func TestCallbackFunc_WithContext(t *testing.T) {
+	if CallbackFunc(nil).WithContext() != nil {
+		t.Fatal("nil CallbackFunc should adapt to nil")
+	}
+	cb := CallbackFunc(func(key string) ([]byte, error) {
+		return []byte("value-" + key), nil
+	}).WithContext()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if v, err := cb(ctx, "k"); err != nil || string(v) != "value-k" {
+		t.Fatalf("adapted callback should ignore ctx, got %q, %v", v, err)
+	}
+	if v, err := cb.Get("k"); err != nil || string(v) != "value-k" {
+		t.Fatalf("unexpected Get result %q, %v", v, err)
+	}
+}
+
 // ---------- Benchmark ----------
 
 func BenchmarkCallbackFunc_Get(b *testing.B) {
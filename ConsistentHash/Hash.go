@@ -1,6 +1,11 @@
 package consistenthash
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
 	"hash/crc32"
 	"sort"
 	"strconv"
@@ -8,11 +13,27 @@ import (
 
 type Hash func(data []byte) uint32
 
+// DefaultReplicas 是 HttpAddr 与 GrpcAddr 未指定时每个节点的虚拟节点数
+const DefaultReplicas = 50
+
+// Keyed 返回以 seed 为密钥的 HMAC-SHA256 哈希（取前 4 字节）。默认的 crc32 可被构造出大量冲突，
+// 不知道 seed 的攻击者无法预测 Keyed 的结果。同一集群的所有节点必须使用相同的 seed，
+// 否则各节点对 key 归属的判断不一致。
+func Keyed(seed []byte) Hash {
+	key := append([]byte(nil), seed...)
+	return func(data []byte) uint32 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return binary.BigEndian.Uint32(mac.Sum(nil))
+	}
+}
+
 type Map struct {
 	hash     Hash
 	replicas int
 	keys     []int // Sorted
 	hashMap  map[int]string
+	nodes    map[string]int // 真实节点 -> 虚拟节点数
 }
 
 func New(replicas int, fn Hash) *Map {
@@ -20,6 +41,7 @@ func New(replicas int, fn Hash) *Map {
 		replicas: replicas,
 		hash:     fn,
 		hashMap:  make(map[int]string),
+		nodes:    make(map[string]int),
 	}
 	if m.hash == nil {
 		m.hash = crc32.ChecksumIEEE
@@ -27,17 +49,82 @@ func New(replicas int, fn Hash) *Map {
 	return m
 }
 
+// Clone 返回哈希环的独立副本，之后对任一方的增删不会影响另一方
+func (m *Map) Clone() *Map {
+	c := &Map{
+		hash:     m.hash,
+		replicas: m.replicas,
+		keys:     append([]int(nil), m.keys...),
+		hashMap:  make(map[int]string, len(m.hashMap)),
+		nodes:    make(map[string]int, len(m.nodes)),
+	}
+	for hash, node := range m.hashMap {
+		c.hashMap[hash] = node
+	}
+	for node, replicas := range m.nodes {
+		c.nodes[node] = replicas
+	}
+	return c
+}
+
 func (m *Map) AddKeys(keys ...string) {
 	for _, key := range keys {
-		for i := 0; i < m.replicas; i++ {
-			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
-			m.keys = append(m.keys, hash)
-			m.hashMap[hash] = key
+		m.addReplicas(key, m.replicas)
+	}
+	sort.Ints(m.keys)
+}
+
+// AddWeightedKeys 添加带权重的节点，每个节点的虚拟节点数为 replicas*weight，
+// 权重小于 1 时按 1 处理
+func (m *Map) AddWeightedKeys(weights map[string]int) {
+	for key, weight := range weights {
+		if weight < 1 {
+			weight = 1
 		}
+		m.addReplicas(key, m.replicas*weight)
 	}
 	sort.Ints(m.keys)
 }
 
+// Remove 从哈希环中删除节点的全部虚拟节点，只有原本属于该节点的 key 会改变归属
+func (m *Map) Remove(key string) {
+	if _, ok := m.nodes[key]; !ok {
+		return
+	}
+	delete(m.nodes, key)
+	// 被删节点可能在哈希冲突中压过了其他节点，重建以恢复这些虚拟节点
+	m.keys = m.keys[:0]
+	m.hashMap = make(map[int]string, len(m.hashMap))
+	for node, replicas := range m.nodes {
+		m.addReplicas(node, replicas)
+	}
+	sort.Ints(m.keys)
+}
+
+// Weight 返回节点的权重（虚拟节点数 / replicas），节点不在哈希环中时返回 0
+func (m *Map) Weight(key string) int {
+	if m.replicas <= 0 {
+		return 0
+	}
+	return m.nodes[key] / m.replicas
+}
+
+// addReplicas 添加虚拟节点，哈希冲突时名字较小的节点胜出，保证结果与添加顺序无关
+func (m *Map) addReplicas(key string, replicas int) {
+	if replicas > m.nodes[key] {
+		m.nodes[key] = replicas
+	}
+	for i := 0; i < replicas; i++ {
+		hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
+		owner, ok := m.hashMap[hash]
+		if !ok {
+			m.keys = append(m.keys, hash)
+		}
+		if !ok || key < owner {
+			m.hashMap[hash] = key
+		}
+	}
+}
 
 func (m *Map) Get(key string) string {
 	if len(m.keys) == 0 {
@@ -50,4 +137,68 @@ func (m *Map) Get(key string) string {
 	})
 
 	return m.hashMap[m.keys[idx%len(m.keys)]]
-}
\ No newline at end of file
+}
+
+// GetN 返回从 key 所在位置起沿环顺时针遇到的至多 n 个不同真实节点
+func (m *Map) GetN(key string, n int) []string {
+	if len(m.keys) == 0 || n <= 0 {
+		return nil
+	}
+
+	hash := int(m.hash([]byte(key)))
+	idx := sort.Search(len(m.keys), func(i int) bool {
+		return m.keys[i] >= hash
+	})
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < len(m.keys) && len(nodes) < n; i++ {
+		node := m.hashMap[m.keys[(idx+i)%len(m.keys)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Digest 返回哈希环状态的稳定摘要（SHA-256 十六进制），由排序后的节点、各节点虚拟节点数
+// 以及虚拟节点在环上的位置计算，与节点的添加顺序无关。两个环的 Digest 相同即路由结果一致，
+// 哈希函数（如 Keyed 的 seed）不同也会体现在摘要中。
+func (m *Map) Digest() string {
+	nodes := make([]string, 0, len(m.nodes))
+	for node := range m.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "replicas=%d\n", m.replicas)
+	for _, node := range nodes {
+		fmt.Fprintf(h, "node=%q %d\n", node, m.nodes[node])
+	}
+	for _, hash := range m.keys {
+		fmt.Fprintf(h, "%d=%q\n", hash, m.hashMap[hash])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Move 描述一个 key 在两个哈希环之间的归属变化
+type Move struct {
+	Key  string
+	From string
+	To   string
+}
+
+// Diff 返回 keys 中在 old 与 new 两个哈希环上归属节点不同的 key
+func Diff(old, new *Map, keys []string) []Move {
+	var moves []Move
+	for _, key := range keys {
+		from, to := old.Get(key), new.Get(key)
+		if from != to {
+			moves = append(moves, Move{Key: key, From: from, To: to})
+		}
+	}
+	return moves
+}
@@ -1,6 +1,7 @@
 package consistenthash
 
 import (
+	"sort"
 	"strconv"
 	"testing"
 )
@@ -40,4 +41,184 @@ func TestHashing(t *testing.T) {
 		}
 	}
 
-}
\ No newline at end of file
+}
+func TestGetN(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.AddKeys("6", "4", "2")
+
+	if got := hash.GetN("11", 3); len(got) != 3 || got[0] != "2" || got[1] != "4" || got[2] != "6" {
+		t.Errorf("GetN(11, 3) = %v, want [2 4 6]", got)
+	}
+	if got := hash.GetN("25", 2); len(got) != 2 || got[0] != "6" || got[1] != "2" {
+		t.Errorf("GetN(25, 2) = %v, want [6 2]", got)
+	}
+	if got := hash.GetN("1", 10); len(got) != 3 {
+		t.Errorf("GetN should cap at the number of real nodes, got %v", got)
+	}
+	if got := New(3, nil).GetN("1", 2); got != nil {
+		t.Errorf("GetN on empty ring should be nil, got %v", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	hashFn := func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	}
+	old := New(3, hashFn)
+	old.AddKeys("6", "4", "2")
+	new := New(3, hashFn)
+	new.AddKeys("6", "4", "2", "8")
+
+	// 只有 27 从 2 移到了 8
+	moves := Diff(old, new, []string{"2", "11", "23", "27"})
+	if len(moves) != 1 {
+		t.Fatalf("expected 1 move, got %v", moves)
+	}
+	if m := moves[0]; m.Key != "27" || m.From != "2" || m.To != "8" {
+		t.Errorf("unexpected move %+v", m)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	hash := New(3, func(key []byte) uint32 {
+		i, _ := strconv.Atoi(string(key))
+		return uint32(i)
+	})
+
+	// 2, 4, 6, 12, 14, 16, 22, 24, 26
+	hash.AddKeys("6", "4", "2")
+	hash.Remove("4")
+
+	// 原本属于 4 的 23 移到 6，其余 key 不变
+	testCases := map[string]string{
+		"2":  "2",
+		"11": "2",
+		"23": "6",
+		"27": "2",
+	}
+	for k, v := range testCases {
+		if hash.Get(k) != v {
+			t.Errorf("Asking for %s, should have yielded %s", k, v)
+		}
+	}
+	if !sort.IntsAreSorted(hash.keys) || len(hash.keys) != 6 {
+		t.Errorf("keys should stay sorted with 6 replicas left, got %v", hash.keys)
+	}
+
+	hash.Remove("2")
+	hash.Remove("6")
+	if got := hash.Get("1"); got != "" {
+		t.Errorf("empty ring should yield \"\", got %q", got)
+	}
+}
+
+func TestRemove_OnlyMovesRemovedNode(t *testing.T) {
+	before := New(50, nil)
+	before.AddKeys("node1", "node2", "node3", "node4")
+	after := before.Clone()
+	after.Remove("node3")
+	after.Remove("missing") // 删除不存在的节点不影响哈希环
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = "key" + strconv.Itoa(i)
+	}
+	moves := Diff(before, after, keys)
+	if len(moves) == 0 {
+		t.Fatal("keys owned by the removed node should move")
+	}
+	for _, m := range moves {
+		if m.From != "node3" {
+			t.Errorf("%s moved from %s, only node3's keys should move", m.Key, m.From)
+		}
+	}
+	if !sort.IntsAreSorted(after.keys) || len(after.keys) != 150 {
+		t.Errorf("keys should stay sorted with 150 replicas left, got %d", len(after.keys))
+	}
+}
+
+func TestCollisionTieBreak(t *testing.T) {
+	// 所有虚拟节点都落在同一个哈希值上，强制冲突
+	collide := func(key []byte) uint32 { return 42 }
+
+	a := New(3, collide)
+	a.AddKeys("b", "a", "c")
+	b := New(3, collide)
+	b.AddKeys("c", "b", "a")
+
+	for _, key := range []string{"x", "y", "z"} {
+		if a.Get(key) != "a" || b.Get(key) != "a" {
+			t.Errorf("collision on %s should resolve to a, got %q and %q", key, a.Get(key), b.Get(key))
+		}
+	}
+	if len(a.keys) != 1 {
+		t.Errorf("colliding replicas should share one ring slot, got %v", a.keys)
+	}
+
+	// 删除胜出的节点后，冲突中落败的节点应接管该位置
+	a.Remove("a")
+	if got := a.Get("x"); got != "b" {
+		t.Errorf("after removing a, should yield b, got %q", got)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	a := New(50, nil)
+	a.AddKeys("node1", "node2", "node3")
+	b := New(50, nil)
+	b.AddKeys("node3", "node1", "node2")
+	if a.Digest() != b.Digest() {
+		t.Fatal("digest should not depend on insertion order")
+	}
+
+	b.Remove("node2")
+	if a.Digest() == b.Digest() {
+		t.Fatal("digest should change when a node is removed")
+	}
+	b.AddKeys("node2")
+	if a.Digest() != b.Digest() {
+		t.Fatal("digest should match again after re-adding the node")
+	}
+
+	c := New(40, nil)
+	c.AddKeys("node1", "node2", "node3")
+	if a.Digest() == c.Digest() {
+		t.Fatal("digest should depend on the replica count")
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	h1, h2 := Keyed([]byte("seed-1")), Keyed([]byte("seed-1"))
+	other := Keyed([]byte("seed-2"))
+
+	differs := false
+	for _, key := range []string{"Tom", "Jack", "Sam"} {
+		if h1([]byte(key)) != h2([]byte(key)) {
+			t.Errorf("same seed should hash %s identically", key)
+		}
+		if h1([]byte(key)) != other([]byte(key)) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Error("different seeds should produce different hashes")
+	}
+
+	// 相同 seed 的两个环对 key 的归属一致
+	a := New(50, Keyed([]byte("seed-1")))
+	a.AddKeys("node1", "node2", "node3")
+	b := New(50, Keyed([]byte("seed-1")))
+	b.AddKeys("node3", "node1", "node2")
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		if a.Get(key) != b.Get(key) {
+			t.Fatalf("rings with the same seed disagree on %s", key)
+		}
+	}
+}
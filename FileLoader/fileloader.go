@@ -0,0 +1,80 @@
+// Package fileloader 提供从只读文件加载数据的回调函数，适用于静态资源、查找表等预先生成的数据集
+package fileloader
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	callbackfunc "geecache/CallbackFunc"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FromDir 返回以 dir 下的文件为数据源的回调函数：key 是相对 dir 的文件路径，值是文件内容。
+// 越出 dir 的 key（绝对路径、含 ".." 等）被拒绝；文件不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)。
+func FromDir(dir string) callbackfunc.CallbackFunc {
+	return func(key string) ([]byte, error) {
+		if !filepath.IsLocal(key) {
+			return nil, fmt.Errorf("fileloader: invalid key %q", key)
+		}
+		return os.ReadFile(filepath.Join(dir, key))
+	}
+}
+
+// span 是值在索引文件中的位置
+type span struct {
+	off int64
+	len int
+}
+
+// FromIndexedFile 返回以单个索引文件为数据源的回调函数。文件每行是一条 "key\tvalue"，
+// 值中不能包含换行；创建时扫描一遍建立 key 到值偏移的索引，之后每次加载只读取对应的值。
+// key 重复时以最后一行为准；key 不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)。
+func FromIndexedFile(path string) (callbackfunc.CallbackFunc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	index := make(map[string]span)
+	r := bufio.NewReader(f)
+	var off int64
+	for line := 1; ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		text := bytes.TrimSuffix(bytes.TrimSuffix(raw, []byte("\n")), []byte("\r"))
+		if len(text) > 0 {
+			tab := bytes.IndexByte(text, '\t')
+			if tab < 0 {
+				return nil, fmt.Errorf("fileloader: %s:%d: missing tab separator", path, line)
+			}
+			index[string(text[:tab])] = span{off: off + int64(tab) + 1, len: len(text) - tab - 1}
+		}
+		off += int64(len(raw))
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return func(key string) ([]byte, error) {
+		s, ok := index[key]
+		if !ok {
+			return nil, fmt.Errorf("fileloader: key %q: %w", key, fs.ErrNotExist)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		value := make([]byte, s.len)
+		if _, err := f.ReadAt(value, s.off); err != nil {
+			return nil, err
+		}
+		return value, nil
+	}, nil
+}
@@ -0,0 +1,54 @@
+package fileloader
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ---------- 目录数据源测试 ----------
+
+func TestFromDir(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "Tom"), []byte("630"), 0o644)
+
+	load := FromDir(dir)
+	if v, err := load("sub/Tom"); err != nil || string(v) != "630" {
+		t.Fatalf("expected 630, got %q %v", v, err)
+	}
+	if _, err := load("Nobody"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing key should be ErrNotExist, got %v", err)
+	}
+	if _, err := load("../outside"); err == nil {
+		t.Fatal("key escaping dir should be rejected")
+	}
+}
+
+// ---------- 索引文件数据源测试 ----------
+
+func TestFromIndexedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.tsv")
+	os.WriteFile(path, []byte("Tom\t630\r\nJack\t589\n\nSam\t\nTom\t631\n"), 0o644)
+
+	load, err := FromIndexedFile(path)
+	if err != nil {
+		t.Fatalf("FromIndexedFile failed: %v", err)
+	}
+	for key, want := range map[string]string{"Tom": "631", "Jack": "589", "Sam": ""} {
+		if v, err := load(key); err != nil || string(v) != want {
+			t.Errorf("%s: expected %q, got %q %v", key, want, v, err)
+		}
+	}
+	if _, err := load("Nobody"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing key should be ErrNotExist, got %v", err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.tsv")
+	os.WriteFile(bad, []byte("no-tab\n"), 0o644)
+	if _, err := FromIndexedFile(bad); err == nil {
+		t.Fatal("line without tab should be rejected")
+	}
+}
@@ -0,0 +1,46 @@
+package group_test
+
+import (
+	"fmt"
+	callbackfunc "geecache/CallbackFunc"
+	group "geecache/Group"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+)
+
+// memTransport 是一个不走 HTTP 的节点传输示例：远程节点用进程内的 map 模拟，
+// 换成 Redis、消息队列等只需实现同样的 PeerPicker 与 PeerGetter 接口
+type memTransport struct {
+	remote map[string]string // 远程节点上的数据
+}
+
+func (t memTransport) PickPeer(key string) (pickpeer.PeerGetter, bool) {
+	if _, ok := t.remote[key]; !ok {
+		return nil, false // 交给本节点处理
+	}
+	return memPeer(t), true
+}
+
+type memPeer memTransport
+
+func (p memPeer) Get(in *pb.Request, out *pb.Response) error {
+	v, ok := p.remote[in.GetKey()]
+	if !ok {
+		return fmt.Errorf("%s not found on peer", in.GetKey())
+	}
+	out.Value = []byte(v)
+	return nil
+}
+
+func ExampleGroup_RegisterPeers() {
+	g := group.NewGroup("example_transport", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("local-" + key), nil
+		}))
+	g.RegisterPeers(memTransport{remote: map[string]string{"Tom": "630"}})
+
+	tom, _ := g.Get("Tom")
+	jack, _ := g.Get("Jack")
+	fmt.Println(tom.String(), jack.String())
+	// Output: 630 local-Jack
+}
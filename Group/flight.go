@@ -0,0 +1,104 @@
+package group
+
+import (
+	"context"
+	singleflight "geecache/SingleFlight"
+	"sync"
+	"time"
+)
+
+// flight 是同一 key 的并发加载共享的 context：携带发起加载的调用方 ctx 中的值，
+// 截止时间取各等待者中最晚的一个，所有等待者都放弃等待后才被取消。
+// 单个调用方取消只会让它自己提前返回，不会中断其他调用方仍在等待的加载。
+type flight struct {
+	context.Context
+	cancel context.CancelFunc
+	key    string // singleflight 与 Group.flights 使用的键，见 flightKey
+
+	mu        sync.Mutex
+	waiters   int
+	deadline  time.Time
+	unbounded bool // 有等待者没有截止时间
+}
+
+// Deadline 返回各等待者中最晚的截止时间，有等待者没有截止时间时返回 false
+func (f *flight) Deadline() (time.Time, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.unbounded {
+		return time.Time{}, false
+	}
+	return f.deadline, true
+}
+
+// add 登记一个等待者，调用方需持有 Group.flightMu
+func (f *flight) add(ctx context.Context) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.waiters++
+	if d, ok := ctx.Deadline(); !ok {
+		f.unbounded = true
+	} else if d.After(f.deadline) {
+		f.deadline = d
+	}
+}
+
+// flightKey 返回 key 的共享加载使用的键。转发来的请求只在本节点回源，与本节点发起的加载行为不同，
+// 二者分开合并，避免共享 ctx 携带的转发标记使其他调用方也跳过 PickPeer
+func flightKey(ctx context.Context, key string) string {
+	if forwarded, _ := ctx.Value(forwardedKey{}).(bool); forwarded {
+		return "f" + key
+	}
+	return "l" + key
+}
+
+// loadChan 发起或加入 key 的共享加载，返回接收结果的 channel 与加载使用的 flight。
+// 调用方拿到结果或放弃等待后都必须调用 leave。
+func (g *Group) loadChan(ctx context.Context, key string) (<-chan singleflight.Result, *flight) {
+	g.flightMu.Lock()
+	defer g.flightMu.Unlock()
+	fkey := flightKey(ctx, key)
+	f, ok := g.flights[fkey]
+	if !ok {
+		loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{Context: loadCtx, cancel: cancel, key: fkey}
+		if g.flights == nil {
+			g.flights = make(map[string]*flight)
+		}
+		g.flights[fkey] = f
+	}
+	f.add(ctx)
+	ch := g.loader.DoChan(fkey, func() (interface{}, error) {
+		defer g.finish(f)
+		return g.load(f, key)
+	})
+	return ch, f
+}
+
+// finish 在加载结束后移除 flight，之后的调用方发起新的加载
+func (g *Group) finish(f *flight) {
+	g.flightMu.Lock()
+	defer g.flightMu.Unlock()
+	if g.flights[f.key] == f {
+		delete(g.flights, f.key)
+	}
+}
+
+// leave 注销一个等待者。最后一个等待者离开时取消加载使用的 ctx；若加载仍在进行，
+// 还会让之后的调用方发起新的加载，而不是加入这次正被取消的加载
+func (g *Group) leave(f *flight) {
+	g.flightMu.Lock()
+	defer g.flightMu.Unlock()
+	f.mu.Lock()
+	f.waiters--
+	last := f.waiters == 0
+	f.mu.Unlock()
+	if !last {
+		return
+	}
+	f.cancel()
+	if g.flights[f.key] == f {
+		delete(g.flights, f.key)
+		g.loader.Forget(f.key)
+	}
+}
@@ -1,43 +1,171 @@
 package group
 
 import (
+	"context"
 	cache "geecache/Cache"
 	callbackfunc "geecache/CallbackFunc"
 	pickpeer "geecache/PickPeer"
 	singleflight "geecache/SingleFlight"
 	pb "geecache/geecachepb"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Group struct {
-	cache  *cache.Cache
-	f      callbackfunc.CallbackFunc
+	cache  cache.Cacher
+	f      callbackfunc.CallbackFuncCtx
 	name   string
 	peers  pickpeer.PeerPicker
 	loader *singleflight.Group
+
+	flightMu sync.Mutex
+	flights  map[string]*flight // 正在进行的共享加载，见 loadChan
+
+	peersDisabled atomic.Bool // 为 true 时 Get 不访问 peers，见 EnablePeers
+	retry         atomic.Pointer[RetryPolicy] // 从远程节点获取失败后的重试策略，见 SetRetryPolicy
+	serveStale    bool
+
+	tags []string
+
+	noLoader bool
+
+	onMiss         func(key string)
+	missSampleRate float64
+
+	version atomic.Int64
+
+	softMaxAge time.Duration
+	refreshing sync.Map // 正在后台刷新的 key
+
+	ttl         time.Duration // 回源加载的值的有效期，0 表示不过期
+	negative    *cache.Cache  // 回调返回 ErrNotFound 的 key，nil 表示未开启负缓存
+	negativeTTL time.Duration
+
+	compressThreshold int // 不小于该字节数的值压缩存储，0 表示不压缩
+
+	multiConcurrency int // GetMulti 同时加载的 key 数上限，0 表示默认值，见 WithMultiConcurrency
+
+	// 访问计数，见 Stats
+	gets       atomic.Int64
+	localHits  atomic.Int64
+	peerHits   atomic.Int64
+	loads      atomic.Int64
+	loadErrors atomic.Int64
 }
 
+// ErrCacheMiss 表示没有回调函数的 Group 在本地和远程节点均未命中
+var ErrCacheMiss = pickpeer.ErrCacheMiss
+
+// ErrNotFound 表示数据源中不存在该 key。回调函数可以直接返回或包装该错误，
+// 开启 WithNegativeTTL 后这类结果会在有效期内被缓存。
+var ErrNotFound = pickpeer.ErrNotFound
+
+// defaultNegativeTTL 是负缓存条目的默认有效期
+const defaultNegativeTTL = 5 * time.Second
+
+// negativeCacheBytes 是负缓存的容量，超出时按 LRU 淘汰
+const negativeCacheBytes = 1 << 20
+
+// RejectedError 表示并发限制或限流拒绝了本次加载，RetryAfter 是限流器估计的等待时间。
+// 回调函数可以直接返回或包装该错误，Serve 会据此返回 503 与 Retry-After。
+type RejectedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("geecache: load rejected, retry after %v", e.RetryAfter)
+}
+
+// errPoolFull 表示共享后台任务池队列已满，任务未被执行
+var errPoolFull = errors.New("geecache: worker pool full")
+
+// replicaTimeout 是 SetWithReplicas 等待副本确认的最长时间
+var replicaTimeout = 3 * time.Second
+
+// previousOwnerTimeout 是哈希环变更期间向旧归属节点取值的最长等待时间，超时后回源加载
+var previousOwnerTimeout = 200 * time.Millisecond
+
 var (
 	mu     sync.RWMutex
 	groups = make(map[string]*Group)
+	// reserved 是 ReserveName 保留的名字，由 mu 保护
+	reserved = make(map[string]bool)
 )
 
-func NewGroup(name string, cache_bytes int64, f callbackfunc.CallbackFunc) *Group {
-	if f == nil {
-		panic("should need callback function")
+// ReserveName 保留 name，之后以它创建 Group 会 panic。节点传输用它保留与自身路由冲突的名字，
+// 如 httpserver 的管理接口 Path/_admin/...
+func ReserveName(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	reserved[name] = true
+}
+
+// checkName 在 name 已被保留时 panic，调用方需持有 mu
+func checkName(name string) {
+	if reserved[name] {
+		panic(fmt.Sprintf("geecache: group name %q is reserved", name))
 	}
+}
+
+func NewGroup(name string, cache_bytes int64, f callbackfunc.CallbackFunc, opts ...Option) *Group {
+	return NewGroupContext(name, cache_bytes, f.WithContext(), opts...)
+}
+
+// NewGroupContext 与 NewGroup 相同，但回调函数接收 GetContext 传入的 ctx，
+// 调用方取消或超时后回调可以及时放弃对数据源的查询
+func NewGroupContext(name string, cache_bytes int64, f callbackfunc.CallbackFuncCtx, opts ...Option) *Group {
+	g := newGroup(name, &cache.Cache{Cache_bytes: cache_bytes}, f, opts...)
 	mu.Lock()
 	defer mu.Unlock()
+	checkName(name)
+	groups[name] = g
+	return g
+}
+
+// NewGroupWithCache 与 NewGroup 相同，但使用调用方提供的本地缓存 c。
+// Pin、Range、Snapshot、Bytes 与统计等功能仅在 c 为 *cache.Cache 时可用，
+// 否则 Pin 返回错误、Range/Snapshot 为空、Bytes 为 0；SetNX 退化为非原子的先 Get 后 Add。
+func NewGroupWithCache(name string, c cache.Cacher, f callbackfunc.CallbackFunc, opts ...Option) *Group {
+	g := newGroup(name, c, f.WithContext(), opts...)
+	mu.Lock()
+	defer mu.Unlock()
+	checkName(name)
+	groups[name] = g
+	return g
+}
+
+// GetOrCreateGroup 原子地返回名为 name 的 Group，不存在时才按参数创建。
+// 并发调用时只有第一个调用方的参数生效，所有调用方拿到同一个实例。
+func GetOrCreateGroup(name string, cache_bytes int64, f callbackfunc.CallbackFunc, opts ...Option) *Group {
+	mu.Lock()
+	defer mu.Unlock()
+	if g, ok := groups[name]; ok {
+		return g
+	}
+	checkName(name)
+	g := newGroup(name, &cache.Cache{Cache_bytes: cache_bytes}, f.WithContext(), opts...)
+	groups[name] = g
+	return g
+}
+
+func newGroup(name string, c cache.Cacher, f callbackfunc.CallbackFuncCtx, opts ...Option) *Group {
 	g := &Group{
-		cache: &cache.Cache{
-			Cache_bytes: cache_bytes,
-		},
+		cache:  c,
 		f:      f,
 		name:   name,
 		loader: &singleflight.Group{},
 	}
-	groups[name] = g
+	for _, opt := range opts {
+		opt(g)
+	}
+	if f == nil && !g.noLoader {
+		panic("should need callback function")
+	}
 	return g
 }
 
@@ -47,6 +175,79 @@ func GetGroup(name string) *Group {
 	mu.RUnlock()
 	return g
 }
+// RemoveGroup 从注册表中删除名为 name 的 Group 并清空其本地缓存，name 不存在时什么也不做。
+// 删除后 GetGroup 返回 nil，Serve 对该 Group 的请求返回 404；仍持有该 *Group 的调用方可以继续使用它，
+// 但它不再出现在 ListGroups 与共享的过期清理中。可以用同一名称重新创建 Group。
+func RemoveGroup(name string) {
+	mu.Lock()
+	g, ok := groups[name]
+	delete(groups, name)
+	mu.Unlock()
+	if !ok {
+		return
+	}
+	g.Clear()
+}
+
+// ListGroups 返回当前已注册的所有 Group 名称
+func ListGroups() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	return names
+}
+
+// TotalCacheBytes 返回所有已注册 Group 本地缓存已使用字节数之和
+func TotalCacheBytes() int64 {
+	mu.RLock()
+	defer mu.RUnlock()
+	var total int64
+	for _, g := range groups {
+		total += g.Bytes()
+	}
+	return total
+}
+
+// StatsByTag 返回所有带有 tag 标签的 Group 的本地缓存统计，key 为 Group 名称。
+// 使用外部缓存的 Group 不在结果中。
+func StatsByTag(tag string) map[string]cache.CacheStats {
+	mu.RLock()
+	defer mu.RUnlock()
+	stats := make(map[string]cache.CacheStats)
+	for name, g := range groups {
+		if !g.HasTag(tag) {
+			continue
+		}
+		if c, ok := g.local(); ok {
+			stats[name] = c.Stats()
+		}
+	}
+	return stats
+}
+
+// Tags 返回 Group 的标签
+func (g *Group) Tags() []string {
+	return append([]string(nil), g.tags...)
+}
+
+// HasTag 判断 Group 是否带有 tag 标签
+func (g *Group) HasTag(tag string) bool {
+	for _, t := range g.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Name 返回 Group 的名称
+func (g *Group) Name() string {
+	return g.name
+}
+
 func (g *Group) RegisterPeers(peers pickpeer.PeerPicker) {
 	if g.peers != nil {
 		panic("RegisterPeerPicker called more than once")
@@ -54,37 +255,582 @@ func (g *Group) RegisterPeers(peers pickpeer.PeerPicker) {
 	g.peers = peers
 }
 
+// EnablePeers 运行时开关 Get 是否访问远程节点，关闭后只使用本地缓存与回调函数，
+// 已注册的 PeerPicker 保留不变，可随时重新开启。对并发的 Get 立即生效。
+func (g *Group) EnablePeers(enabled bool) {
+	g.peersDisabled.Store(!enabled)
+}
+
 func (g *Group) Get(key string) (cache.ByteView, error) {
-	view, err := g.loader.Do(key, func() (interface{}, error) {
-		if g.peers != nil {
-			if peer, ok := g.peers.PickPeer(key); ok {
-				if bytes, err := g.getFromPeer(peer, key); err == nil {
-					return cache.NewByteView(bytes), nil
+	return g.GetContext(context.Background(), key)
+}
+
+// GetContext 与 Get 相同，未命中时 ctx 结束会立即返回 ctx.Err()，不再等待加载完成。
+// 并发的相同请求共享同一次加载，加载使用的 ctx 携带发起加载的调用方 ctx 中的值，
+// 传给远程节点请求（PeerGetter 实现 pickpeer.PeerGetterContext 时）与 NewGroupContext 注册的回调函数；
+// 它在所有等待的调用方都放弃后才被取消，单个调用方取消不影响其他调用方。
+func (g *Group) GetContext(ctx context.Context, key string) (cache.ByteView, error) {
+	res, err := g.getResult(ctx, key)
+	return res.Value, err
+}
+
+// Stat 返回本地缓存中 key 的元数据而不返回值，不会触发加载，也不影响淘汰顺序。
+// key 未缓存时返回 false。
+func (g *Group) Stat(key string) (EntryStat, bool) {
+	var v cache.ByteView
+	var ok bool
+	if c, local := g.local(); local {
+		v, ok = c.Peek(key)
+	} else {
+		v, ok = g.cache.Get(key)
+	}
+	if !ok {
+		return EntryStat{}, false
+	}
+	meta := v.Meta()
+	st := EntryStat{Size: v.Len(), CreatedAt: meta.CreatedAt, Version: meta.Version}
+	if expire := v.ExpiresAt(); !expire.IsZero() {
+		st.TTL = time.Until(expire)
+	}
+	return st, true
+}
+
+// GetResult 与 Get 相同，但额外返回本次访问的命中情况、数据来源以及是否与并发请求共享了加载结果
+func (g *Group) GetResult(key string) (Result, error) {
+	return g.getResult(context.Background(), key)
+}
+
+func (g *Group) getResult(ctx context.Context, key string) (Result, error) {
+	if res, ok, err := g.lookup(key); ok {
+		return res, err
+	}
+	return g.loadResult(ctx, key)
+}
+
+// lookup 在本地缓存与负缓存中查找 key 并计入访问统计，命中时按 WithSoftMaxAge 触发后台刷新。
+// 未命中时返回 false，由调用方通过 loadResult 加载
+func (g *Group) lookup(key string) (Result, bool, error) {
+	g.gets.Add(1)
+	if v, ok := g.cache.Get(key); ok {
+		g.localHits.Add(1)
+		if g.softMaxAge > 0 && !v.Meta().CreatedAt.IsZero() && time.Since(v.Meta().CreatedAt) > g.softMaxAge {
+			g.refresh(key)
+		}
+		return Result{Value: v, Meta: v.Meta(), Hit: true, Source: SourceCache}, true, nil
+	}
+	if g.negative != nil {
+		if _, ok := g.negative.Get(key); ok {
+			g.localHits.Add(1)
+			return Result{Hit: true, Source: SourceCache, Negative: true}, true, ErrNotFound
+		}
+	}
+	return Result{}, false, nil
+}
+
+// loadResult 经由 singleflight 加载本地未命中的 key，ctx 结束时不再等待
+func (g *Group) loadResult(ctx context.Context, key string) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	ch, f := g.loadChan(ctx, key)
+	defer g.leave(f)
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return Result{Shared: r.Shared}, r.Err
+		}
+		res := r.Val.(Result)
+		res.Meta = res.Value.Meta()
+		res.Shared = r.Shared
+		return res, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
+
+// forwardedKey 是 Forwarded 在 ctx 中设置的标记
+type forwardedKey struct{}
+
+// Forwarded 返回标记了“请求由其他节点转发而来”的 ctx。以它调用 GetContext 时，
+// 未命中不再通过 PickPeer 转发给其他节点而是在本节点回源，避免发送方与本节点对归属的判断不一致
+// （如 PreferHealthy 选中了非归属节点）时请求被再次转发甚至在节点间来回转发。
+func Forwarded(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forwardedKey{}, true)
+}
+
+// pickPeer 选择 key 的远程节点，请求由其他节点转发而来时返回 false
+func (g *Group) pickPeer(ctx context.Context, key string) (pickpeer.PeerGetter, bool) {
+	if forwarded, _ := ctx.Value(forwardedKey{}).(bool); forwarded {
+		return nil, false
+	}
+	return g.peers.PickPeer(key)
+}
+
+func (g *Group) load(ctx context.Context, key string) (Result, error) {
+	if g.peers != nil && !g.peersDisabled.Load() {
+		if peer, ok := g.pickPeer(ctx, key); ok {
+			value, err := g.getFromPeerWithRetry(ctx, peer, key)
+			if err == nil {
+				g.peerHits.Add(1)
+				return Result{Value: value, Source: SourcePeer}, nil
+			}
+			// 归属节点已确认数据源中没有该 key，本地回源只会得到同样的结果
+			if errors.Is(err, ErrNotFound) {
+				g.rememberMissing(key)
+				return Result{Source: SourcePeer}, err
+			}
+			log.Println("[GeeCache] Failed to get from peer:", err)
+		} else if value, ok := g.getFromPreviousOwner(ctx, key); ok {
+			g.peerHits.Add(1)
+			g.store(key, value)
+			return Result{Value: value, Source: SourcePeer}, nil
+		}
+	}
+	if g.onMiss != nil && rand.Float64() < g.missSampleRate {
+		g.onMiss(key)
+	}
+	if g.f == nil {
+		return Result{}, ErrCacheMiss
+	}
+	// 从回调函数获取数据，需要转换为 ByteView
+	g.loads.Add(1)
+	bytes, err := g.f(ctx, key)
+	if err != nil {
+		g.loadErrors.Add(1)
+		if g.serveStale {
+			if reader, ok := g.cache.(cache.StaleReader); ok {
+				if value, ok := reader.PeekStale(key); ok {
+					log.Println("[GeeCache] Serving stale value of", key, "after load failed:", err)
+					return Result{Value: value, Source: SourceCache, Stale: true}, nil
 				}
-				log.Println("[GeeCache] Failed to get from peer", peer)
 			}
 		}
-		// 从回调函数获取数据，需要转换为 ByteView
-		bytes, err := g.f(key)
-		if err != nil {
-			return cache.ByteView{}, err
+		if errors.Is(err, ErrNotFound) {
+			g.rememberMissing(key)
+		}
+		return Result{}, err
+	}
+	value := g.newValue(bytes)
+	g.store(key, value)
+	return Result{Value: value, Source: SourceLoader}, nil
+}
+
+// store 将回源加载的值写入本地缓存，设置了 WithTTL 且本地缓存支持时带上有效期
+func (g *Group) store(key string, value cache.ByteView) {
+	if c, ok := g.local(); ok && g.ttl > 0 {
+		c.AddWithTTL(key, value, g.ttl)
+		return
+	}
+	g.cache.Add(key, value)
+}
+
+// rememberMissing 在开启 WithNegativeTTL 时为确认不存在的 key 写入负缓存条目
+func (g *Group) rememberMissing(key string) {
+	if g.negative != nil {
+		g.negative.AddWithTTL(key, cache.ByteView{}, g.negativeTTL)
+	}
+}
+
+// forgetMissing 删除 key 的负缓存条目，key 被显式写入或删除后应立即重新查询
+func (g *Group) forgetMissing(key string) {
+	if g.negative != nil {
+		g.negative.Delete(key)
+	}
+}
+
+// refresh 在后台重新加载 key，同一 key 同时只有一个刷新在进行
+func (g *Group) refresh(key string) {
+	if _, busy := g.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	ok := submit(func() {
+		defer g.refreshing.Delete(key)
+		if _, err := g.loader.Do(flightKey(context.Background(), key), func() (interface{}, error) {
+			return g.load(context.Background(), key)
+		}); err != nil {
+			log.Println("[GeeCache] Failed to refresh", key, err)
+		}
+	})
+	if !ok {
+		// 任务池已满时放弃本次刷新，下次命中会再次尝试
+		g.refreshing.Delete(key)
+	}
+}
+
+// newValue 为本地加载或写入的值生成带元数据的 ByteView，开启 WithCompressionThreshold 时
+// 较大的值压缩保存，压缩后没有变小则仍保存原值
+func (g *Group) newValue(bytes []byte) cache.ByteView {
+	value := cache.NewByteView(bytes)
+	if g.compressThreshold > 0 && len(bytes) >= g.compressThreshold {
+		if compressed := cache.NewGzipByteView(bytes); compressed.Len() < value.Len() {
+			value = compressed
 		}
-		return cache.NewByteView(bytes), nil
+	}
+	return value.WithMeta(cache.Meta{
+		CreatedAt: time.Now(),
+		Version:   g.version.Add(1),
 	})
-	if err != nil {
-		return cache.ByteView{}, err
+}
+
+// peerName 返回用于日志与错误信息的节点名：实现了 fmt.Stringer 的节点（如 HttpClient）使用 String，
+// 其他节点只打印类型，避免通过反射读取其字段而与并发的统计更新产生数据竞争
+func peerName(peer pickpeer.PeerGetter) string {
+	if s, ok := peer.(fmt.Stringer); ok {
+		return s.String()
 	}
-	return view.(cache.ByteView), nil
+	return fmt.Sprintf("%T", peer)
 }
-func (g *Group) getFromPeer(peer pickpeer.PeerGetter, key string) ([]byte, error) {
+
+// getFromPeer 向 peer 请求 key，失败时返回的错误带有 group、key 与 peer，便于按 group 检索日志
+func (g *Group) getFromPeer(ctx context.Context, peer pickpeer.PeerGetter, key string) (cache.ByteView, error) {
 	req := &pb.Request{
 		Group: g.name,
 		Key:   key,
 	}
 	res := &pb.Response{}
-	err := peer.Get(req, res)
+	var err error
+	if pc, ok := peer.(pickpeer.PeerGetterContext); ok {
+		err = pc.GetContext(ctx, req, res)
+	} else {
+		err = peer.Get(req, res)
+	}
 	if err != nil {
-		return nil, err
+		return cache.ByteView{}, fmt.Errorf("group %q key %q peer %s: %w", g.name, key, peerName(peer), err)
+	}
+	meta := cache.Meta{Node: res.GetNode(), Version: res.GetVersion()}
+	if res.GetCreatedAt() != 0 {
+		meta.CreatedAt = time.Unix(0, res.GetCreatedAt())
+	}
+	return cache.NewByteView(res.GetValue()).WithMeta(meta), nil
+}
+
+// getFromPreviousOwner 在 peers 支持时向 key 的旧归属节点取值，最多等待 previousOwnerTimeout
+func (g *Group) getFromPreviousOwner(ctx context.Context, key string) (cache.ByteView, bool) {
+	picker, ok := g.peers.(pickpeer.PreviousOwnerPicker)
+	if !ok {
+		return cache.ByteView{}, false
+	}
+	peer, ok := picker.PickPreviousOwner(key)
+	if !ok {
+		return cache.ByteView{}, false
+	}
+
+	type result struct {
+		value cache.ByteView
+		err   error
+	}
+	done := make(chan result, 1)
+	if !submit(func() {
+		value, err := g.getFromPeer(ctx, peer, key)
+		done <- result{value, err}
+	}) {
+		return cache.ByteView{}, false
+	}
+
+	timer := time.NewTimer(previousOwnerTimeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			log.Println("[GeeCache] Failed to get from previous owner:", r.err)
+			return cache.ByteView{}, false
+		}
+		return r.value, true
+	case <-timer.C:
+		log.Printf("[GeeCache] Previous owner timed out: group %q key %q peer %s", g.name, key, peerName(peer))
+		return cache.ByteView{}, false
+	}
+}
+
+// local 返回默认的 LRU 本地缓存，使用外部缓存时返回 false
+func (g *Group) local() (*cache.Cache, bool) {
+	c, ok := g.cache.(*cache.Cache)
+	return c, ok
+}
+
+// Bytes 返回本地缓存已使用的字节数，与 UsedBytes 相同
+func (g *Group) Bytes() int64 {
+	return g.UsedBytes()
+}
+
+// CacheBytes 返回本地缓存的容量上限，0 表示不限容量。
+// 与 UsedBytes 一起可用于发现长期贴近上限、频繁淘汰的 Group。
+// 使用 NewGroupWithCache 提供的外部缓存时返回 0。
+func (g *Group) CacheBytes() int64 {
+	if c, ok := g.local(); ok {
+		return c.Cache_bytes
+	}
+	return 0
+}
+
+// UsedBytes 返回本地缓存已使用的字节数，使用外部缓存时返回 0
+func (g *Group) UsedBytes() int64 {
+	if c, ok := g.local(); ok {
+		return c.Bytes()
+	}
+	return 0
+}
+
+// Len 返回本地缓存的条目数（包括已过期但尚未清理的），使用外部缓存时返回 0
+func (g *Group) Len() int {
+	if c, ok := g.local(); ok {
+		return c.Len()
+	}
+	return 0
+}
+
+// ResetStats 将本地缓存的命中、未命中、淘汰计数、Stats 的访问计数以及请求合并统计清零，便于分段测量
+func (g *Group) ResetStats() {
+	if c, ok := g.local(); ok {
+		c.ResetStats()
+	}
+	g.loader.ResetStats()
+	for _, n := range []*atomic.Int64{&g.gets, &g.localHits, &g.peerHits, &g.loads, &g.loadErrors} {
+		n.Store(0)
+	}
+}
+
+// Stats 返回 Group 访问计数的快照，计数自 Group 创建或上次 ResetStats 起累计
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:       g.gets.Load(),
+		LocalHits:  g.localHits.Load(),
+		PeerHits:   g.peerHits.Load(),
+		Loads:      g.loads.Load(),
+		LoadErrors: g.loadErrors.Load(),
+		Coalesced:  g.loader.Stats().Coalesced,
+	}
+}
+
+// FlightStats 返回回源加载的请求合并统计；开启 WithFlightTiming 后包含领头与跟随调用的平均耗时
+func (g *Group) FlightStats() singleflight.Stats {
+	return g.loader.Stats()
+}
+
+// Range 依次遍历本地缓存，fn 返回 false 时停止遍历。顺序见 cache.Cache.Range：PolicyLRU 下从最近使用到最久未使用
+func (g *Group) Range(fn func(key string, value cache.ByteView) bool) {
+	if c, ok := g.local(); ok {
+		c.Range(fn)
+	}
+}
+
+// Keys 返回本地缓存中全部未过期条目的 key，顺序与 Range 相同
+func (g *Group) Keys() []string {
+	if c, ok := g.local(); ok {
+		return c.Keys()
+	}
+	if c, ok := g.cache.(interface{ Keys() []string }); ok {
+		return c.Keys()
+	}
+	return nil
+}
+
+// Clear 清空本地缓存与负缓存，不影响远程节点。NewGroupWithCache 提供的外部缓存实现了 Clear() 时同样被清空
+func (g *Group) Clear() {
+	if c, ok := g.cache.(interface{ Clear() }); ok {
+		c.Clear()
+	}
+	if g.negative != nil {
+		g.negative.Clear()
+	}
+}
+
+// Pin 加载 key 并将其固定在本地缓存中，使其不会被容量淘汰
+func (g *Group) Pin(key string) error {
+	if _, err := g.Get(key); err != nil {
+		return err
+	}
+	c, ok := g.local()
+	if !ok {
+		return fmt.Errorf("group %s: cache does not support pinning", g.name)
+	}
+	if err := c.Pin(key); err != nil {
+		log.Println("[GeeCache] Failed to pin", key, err)
+		return err
+	}
+	return nil
+}
+
+// Unpin 取消固定 key
+func (g *Group) Unpin(key string) {
+	if c, ok := g.local(); ok {
+		c.Unpin(key)
+	}
+}
+
+// Snapshot 返回本地缓存的时间点只读副本，可在不长时间阻塞 Get/Set 的情况下遍历，
+// 内存开销见 cache.Snapshot
+func (g *Group) Snapshot() *cache.Snapshot {
+	if c, ok := g.local(); ok {
+		return c.Snapshot()
+	}
+	return &cache.Snapshot{}
+}
+
+// Set 将 value 写入本地缓存，返回是否写入；值未被准入或被 RejectNew 策略拒绝时返回 false
+func (g *Group) Set(key string, value []byte) bool {
+	g.forgetMissing(key)
+	if c, ok := g.local(); ok {
+		return c.TryAdd(key, g.newValue(value))
+	}
+	g.cache.Add(key, g.newValue(value))
+	return true
+}
+
+// Remove 删除本地缓存中的 key；注册了 peers 且 key 归远程节点负责时，同时删除该节点上的缓存，
+// 用于数据源更新后立即失效缓存。远程删除失败时返回错误，本地删除仍然生效。
+func (g *Group) Remove(key string) error {
+	g.RemoveLocal(key)
+	if g.peers == nil {
+		return nil
+	}
+	peer, ok := g.peers.PickPeer(key)
+	if !ok {
+		return nil
+	}
+	deleter, ok := peer.(pickpeer.PeerDeleter)
+	if !ok {
+		return fmt.Errorf("group %s: peer %s does not support Delete", g.name, peerName(peer))
+	}
+	return deleter.Delete(&pb.Request{Group: g.name, Key: key})
+}
+
+// RemoveLocal 只删除本地缓存中的 key，返回 key 是否存在。远程节点收到删除请求时调用它，避免再次转发。
+func (g *Group) RemoveLocal(key string) bool {
+	g.forgetMissing(key)
+	if c, ok := g.local(); ok {
+		return c.Delete(key)
+	}
+	if d, ok := g.cache.(interface{ Delete(key string) bool }); ok {
+		return d.Delete(key)
+	}
+	return false
+}
+
+// SetNX 仅当本地缓存中不存在 key 时写入 value，返回是否写入成功
+func (g *Group) SetNX(key string, value []byte) bool {
+	g.forgetMissing(key)
+	if c, ok := g.local(); ok {
+		return c.AddIfAbsent(key, g.newValue(value))
+	}
+	if _, ok := g.cache.Get(key); ok {
+		return false
+	}
+	g.cache.Add(key, g.newValue(value))
+	return true
+}
+
+// GetAllReplicas 读取 key 在至多 n 个副本上的值，不做任何取舍，供调用方自行实现读修复。
+// 本地缓存中的值（若存在）计为一个副本，其余依次取哈希环上 key 之后的远程节点；
+// 远程副本经其 Get 读取，未缓存时可能由该节点加载。失败或超时的远程副本会被跳过，
+// 所有副本都不可用时返回错误。
+func (g *Group) GetAllReplicas(key string, n int) ([]ReplicaValue, error) {
+	var values []ReplicaValue
+	if c, ok := g.local(); ok {
+		if v, ok := c.Peek(key); ok {
+			values = append(values, ReplicaValue{Value: v, Version: v.Meta().Version})
+		}
+	} else if v, ok := g.cache.Get(key); ok {
+		values = append(values, ReplicaValue{Value: v, Version: v.Meta().Version})
+	}
+	remaining := n - len(values)
+
+	var peers []pickpeer.PeerGetter
+	if picker, ok := g.peers.(pickpeer.ReplicaPicker); ok && remaining > 0 {
+		peers = picker.PickPeers(key, remaining)
+	}
+
+	type result struct {
+		value cache.ByteView
+		err   error
+	}
+	results := make(chan result, len(peers))
+	for _, peer := range peers {
+		peer := peer
+		if !submit(func() {
+			v, err := g.getFromPeer(context.Background(), peer, key)
+			results <- result{v, err}
+		}) {
+			results <- result{err: errPoolFull}
+		}
+	}
+
+	timer := time.NewTimer(replicaTimeout)
+	defer timer.Stop()
+collect:
+	for i := 0; i < len(peers); i++ {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				log.Println("[GeeCache] Failed to read replica:", r.err)
+				continue
+			}
+			meta := r.value.Meta()
+			values = append(values, ReplicaValue{Value: r.value, Version: meta.Version, Node: meta.Node})
+		case <-timer.C:
+			log.Println("[GeeCache] Timed out reading replicas of", key)
+			break collect
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("group %s key %s: no replica available", g.name, key)
+	}
+	return values, nil
+}
+
+// SetWithReplicas 将 value 写入本地缓存，并推送到哈希环上 key 之后的 k-1 个远程节点。
+// 本地写入成功时计为一次确认（被准入策略或 RejectNew 拒绝时不计），
+// 若在 replicaTimeout 内收到的确认数少于 k 则返回错误。
+func (g *Group) SetWithReplicas(key string, value []byte, k int) error {
+	acks := 0
+	if g.Set(key, value) {
+		acks = 1
+	}
+	if acks >= k {
+		return nil
+	}
+
+	picker, ok := g.peers.(pickpeer.ReplicaPicker)
+	if !ok {
+		return fmt.Errorf("group %s: peers do not support replication, got %d/%d acks", g.name, acks, k)
+	}
+	peers := picker.PickPeers(key, k-1)
+
+	req := &pb.Request{
+		Group: g.name,
+		Key:   key,
+	}
+	errc := make(chan error, len(peers))
+	for _, peer := range peers {
+		peer := peer
+		if !submit(func() {
+			setter, ok := peer.(pickpeer.PeerSetter)
+			if !ok {
+				errc <- fmt.Errorf("peer %s does not support Set", peerName(peer))
+				return
+			}
+			errc <- setter.Set(req, value)
+		}) {
+			errc <- errPoolFull
+		}
+	}
+
+	timer := time.NewTimer(replicaTimeout)
+	defer timer.Stop()
+	for i := 0; i < len(peers) && acks < k; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				log.Println("[GeeCache] Failed to replicate to peer", err)
+				continue
+			}
+			acks++
+		case <-timer.C:
+			return fmt.Errorf("group %s key %s: replication timed out with %d/%d acks", g.name, key, acks, k)
+		}
+	}
+	if acks < k {
+		return fmt.Errorf("group %s key %s: got %d/%d acks", g.name, key, acks, k)
 	}
-	return res.Value, nil
+	return nil
 }
@@ -0,0 +1,1469 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cache "geecache/Cache"
+	callbackfunc "geecache/CallbackFunc"
+	pickpeer "geecache/PickPeer"
+	singleflight "geecache/SingleFlight"
+	pb "geecache/geecachepb"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ---------- 测试数据 ----------
+
+var db = map[string]string{
+	"Tom":  "630",
+	"Jack": "589",
+	"Sam":  "567",
+}
+
+// ---------- 辅助函数 ----------
+
+func newTestGroup(name string) *Group {
+	return NewGroup(name, 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if v, ok := db[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+}
+
+// ---------- 注册表测试 ----------
+
+func TestGetOrCreateGroup_Concurrent(t *testing.T) {
+	const numGoroutines = 100
+	results := make([]*Group, numGoroutines)
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			results[id] = GetOrCreateGroup("same_name", 2<<10, callbackfunc.CallbackFunc(
+				func(key string) ([]byte, error) {
+					return []byte(fmt.Sprintf("g%d", id)), nil
+				}))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, g := range results {
+		if g != results[0] {
+			t.Fatalf("goroutine %d got a different instance", i)
+		}
+	}
+	if GetGroup("same_name") != results[0] {
+		t.Fatal("registered group should be the shared instance")
+	}
+}
+
+func TestReserveName(t *testing.T) {
+	ReserveName("reserved_test")
+	for name, create := range map[string]func(){
+		"NewGroup": func() {
+			NewGroup("reserved_test", 2<<10, nil, WithoutLoader())
+		},
+		"GetOrCreateGroup": func() {
+			GetOrCreateGroup("reserved_test", 2<<10, nil, WithoutLoader())
+		},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s should panic on a reserved name", name)
+				}
+			}()
+			create()
+		}()
+	}
+	if GetGroup("reserved_test") != nil {
+		t.Fatal("reserved name should not be registered")
+	}
+}
+
+func TestRemoveGroup(t *testing.T) {
+	g := newTestGroup("remove_group_test")
+	g.Get("Tom")
+	has := func() bool {
+		for _, name := range ListGroups() {
+			if name == "remove_group_test" {
+				return true
+			}
+		}
+		return false
+	}
+	if !has() {
+		t.Fatal("new group should be listed")
+	}
+
+	RemoveGroup("remove_group_test")
+	if GetGroup("remove_group_test") != nil || has() {
+		t.Fatal("removed group should not be registered")
+	}
+	if g.Len() != 0 {
+		t.Fatalf("removed group's cache should be cleared, got %d entries", g.Len())
+	}
+	RemoveGroup("remove_group_test")
+
+	if newTestGroup("remove_group_test") == g || GetGroup("remove_group_test") == nil {
+		t.Fatal("name should be reusable after removal")
+	}
+	RemoveGroup("remove_group_test")
+}
+
+func TestGroup_KeysClear(t *testing.T) {
+	g := NewGroup("keys_clear_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, ErrNotFound
+			}
+			return []byte("v-" + key), nil
+		}), WithNegativeTTL(time.Minute))
+	g.Get("a")
+	g.Get("b")
+	g.Get("missing")
+	if keys := g.Keys(); len(keys) != 2 || keys[0] != "b" || keys[1] != "a" {
+		t.Fatalf("expected [b a], got %v", keys)
+	}
+
+	g.Clear()
+	if g.Len() != 0 || len(g.Keys()) != 0 {
+		t.Fatalf("Clear should empty the cache, got %v", g.Keys())
+	}
+	if res, err := g.GetResult("missing"); !errors.Is(err, ErrNotFound) || res.Negative {
+		t.Fatalf("Clear should drop negative entries, got %+v, %v", res, err)
+	}
+}
+
+func TestTotalCacheBytes(t *testing.T) {
+	before := TotalCacheBytes()
+
+	g1 := newTestGroup("bytes_test_1")
+	g2 := newTestGroup("bytes_test_2")
+	g1.Set("k1", []byte("12345"))
+	g2.Set("k2", []byte("123"))
+
+	// 每个条目计入 key 与 value 的长度
+	if got := TotalCacheBytes() - before; got != int64(2+5+2+3) {
+		t.Fatalf("expected 12 more bytes, got %d", got)
+	}
+}
+
+func TestGroup_CacheUsage(t *testing.T) {
+	g := NewGroup("usage_test", 10, nil, WithoutLoader())
+	if g.CacheBytes() != 10 || g.UsedBytes() != 0 || g.Len() != 0 {
+		t.Fatalf("unexpected usage of an empty group: %d/%d, %d entries", g.UsedBytes(), g.CacheBytes(), g.Len())
+	}
+	g.Set("k1", []byte("123"))
+	g.Set("k2", []byte("456"))
+	if g.UsedBytes() != 10 || g.Len() != 2 {
+		t.Fatalf("expected 10 bytes in 2 entries, got %d in %d", g.UsedBytes(), g.Len())
+	}
+	// 超出容量时淘汰最旧的条目
+	g.Set("k3", []byte("789"))
+	if g.UsedBytes() > g.CacheBytes() || g.Len() != 2 {
+		t.Fatalf("expected eviction, got %d bytes in %d entries", g.UsedBytes(), g.Len())
+	}
+}
+
+// ---------- GetResult 测试 ----------
+
+func TestGroup_GetResult(t *testing.T) {
+	g := newTestGroup("result_test")
+
+	res, err := g.GetResult("Tom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Hit || res.Source != SourceLoader || res.Value.String() != "630" {
+		t.Fatalf("expected a loader miss with '630', got %+v", res)
+	}
+
+	res, err = g.GetResult("Tom")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Hit || res.Source != SourceCache || res.Value.String() != "630" {
+		t.Fatalf("expected a cache hit with '630', got %+v", res)
+	}
+
+	if _, err := g.GetResult("Nobody"); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+}
+
+type metaPeers struct{}
+
+func (metaPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return metaGetter{}, true }
+
+type metaGetter struct{}
+
+func (metaGetter) Get(in *pb.Request, out *pb.Response) error {
+	out.Value = []byte("remote")
+	out.CreatedAt = time.Unix(100, 0).UnixNano()
+	out.Node = "http://peer"
+	out.Version = 7
+	return nil
+}
+
+func TestGroup_GetResult_Meta(t *testing.T) {
+	g := newTestGroup("result_meta_test")
+	res, _ := g.GetResult("Tom")
+	if res.Meta.Version == 0 || res.Meta.CreatedAt.IsZero() || res.Meta.Node != "" {
+		t.Fatalf("locally loaded value should carry local metadata, got %+v", res.Meta)
+	}
+	if hit, _ := g.GetResult("Tom"); hit.Meta != res.Meta {
+		t.Fatalf("cache hit should keep metadata %+v, got %+v", res.Meta, hit.Meta)
+	}
+
+	remote := newTestGroup("result_meta_remote_test")
+	remote.RegisterPeers(metaPeers{})
+	res, _ = remote.GetResult("Tom")
+	want := cache.Meta{CreatedAt: time.Unix(100, 0), Node: "http://peer", Version: 7}
+	if !res.Meta.CreatedAt.Equal(want.CreatedAt) || res.Meta.Node != want.Node || res.Meta.Version != want.Version {
+		t.Fatalf("expected peer metadata %+v, got %+v", want, res.Meta)
+	}
+}
+
+func TestGroup_Stat(t *testing.T) {
+	g := newTestGroup("stat_test")
+	if _, ok := g.Stat("Tom"); ok {
+		t.Fatal("Stat should not load missing keys")
+	}
+
+	res, _ := g.GetResult("Tom")
+	st, ok := g.Stat("Tom")
+	if !ok || st.Size != 3 || st.Version != res.Meta.Version || !st.CreatedAt.Equal(res.Meta.CreatedAt) {
+		t.Fatalf("unexpected stat %+v for %+v", st, res.Meta)
+	}
+	c, _ := g.local()
+	if s := c.Stats(); s.Hits != 0 {
+		t.Fatalf("Stat should not count as a hit, got %+v", s)
+	}
+}
+
+func TestGroup_EnablePeers(t *testing.T) {
+	g := newTestGroup("enable_peers_test")
+	g.RegisterPeers(metaPeers{})
+
+	g.EnablePeers(false)
+	if res, _ := g.GetResult("Tom"); res.Source != SourceLoader || res.Value.String() != "630" {
+		t.Fatalf("disabled peers should load from source, got %+v", res)
+	}
+
+	g.EnablePeers(true)
+	if res, _ := g.GetResult("Jack"); res.Source != SourcePeer {
+		t.Fatalf("enabled peers should be consulted, got %+v", res)
+	}
+}
+
+type flakyPeers struct {
+	getter *flakyGetter
+}
+
+func (p flakyPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return p.getter, true }
+
+type flakyGetter struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (f *flakyGetter) Get(in *pb.Request, out *pb.Response) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return fmt.Errorf("flaky")
+	}
+	out.Value = []byte("remote")
+	return nil
+}
+
+func TestGroup_PeerRetries(t *testing.T) {
+	getter := &flakyGetter{failures: 2}
+	g := NewGroup("peer_retries_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("source"), nil
+		}), WithPeerRetries(2))
+	g.RegisterPeers(flakyPeers{getter})
+
+	if res, _ := g.GetResult("k"); res.Source != SourcePeer || getter.calls != 3 {
+		t.Fatalf("should succeed on the third attempt, got %+v after %d calls", res, getter.calls)
+	}
+
+	// 默认不重试，失败一次即回源
+	getter = &flakyGetter{failures: 1}
+	g = newTestGroup("peer_no_retries_test")
+	g.RegisterPeers(flakyPeers{getter})
+	if res, _ := g.GetResult("Tom"); res.Source != SourceLoader || getter.calls != 1 {
+		t.Fatalf("should fall back to source after one failure, got %+v after %d calls", res, getter.calls)
+	}
+}
+
+func TestGroup_GetResult_Shared(t *testing.T) {
+	start := make(chan struct{})
+	g := NewGroup("result_shared_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			<-start
+			return []byte("v"), nil
+		}))
+
+	const numGoroutines = 10
+	results := make(chan Result, numGoroutines)
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			res, _ := g.GetResult("k")
+			results <- res
+		}()
+	}
+	// 等待请求在 singleflight 中汇合后再放行加载
+	time.Sleep(50 * time.Millisecond)
+	close(start)
+	wg.Wait()
+	close(results)
+
+	shared := 0
+	for res := range results {
+		if res.Shared {
+			shared++
+		}
+	}
+	if shared == 0 {
+		t.Fatal("expected concurrent loads to be shared")
+	}
+}
+
+func TestGroup_SoftMaxAge(t *testing.T) {
+	var mu sync.Mutex
+	loads := 0
+	g := NewGroup("soft_max_age_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			loads++
+			return []byte(fmt.Sprintf("v%d", loads)), nil
+		}), WithSoftMaxAge(10*time.Millisecond))
+
+	g.Get("k")
+	time.Sleep(20 * time.Millisecond)
+
+	// 超过软过期时间仍立即返回旧值，并在后台刷新
+	if v, _ := g.Get("k"); v.String() != "v1" {
+		t.Fatalf("stale value should be returned immediately, got %q", v.String())
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, _ := g.cache.Get("k"); v.String() == "v2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("value should be refreshed in the background")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// ---------- 未命中回调测试 ----------
+
+func TestGroup_OnMiss(t *testing.T) {
+	var missed []string
+	g := NewGroup("on_miss_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v"), nil
+		}), WithOnMiss(func(key string) {
+		missed = append(missed, key)
+	}, 1))
+
+	g.Get("a")
+	g.Get("a")
+	g.Get("b")
+
+	if len(missed) != 2 || missed[0] != "a" || missed[1] != "b" {
+		t.Fatalf("expected misses [a b], got %v", missed)
+	}
+
+	// 采样率为 0 时不调用
+	calls := 0
+	g = NewGroup("on_miss_off_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v"), nil
+		}), WithOnMiss(func(key string) { calls++ }, 0))
+	g.Get("a")
+	if calls != 0 {
+		t.Fatalf("expected no calls with sample rate 0, got %d", calls)
+	}
+}
+
+// ---------- 统计测试 ----------
+
+func TestGroup_ResetStats(t *testing.T) {
+	g := newTestGroup("reset_stats_test")
+	g.Get("Tom")
+	g.Get("Tom")
+	g.Get("Nobody")
+	c, _ := g.local()
+
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 {
+		t.Fatalf("unexpected cache stats %+v", s)
+	}
+	if s := g.loader.Stats(); s.Calls != 2 {
+		t.Fatalf("unexpected singleflight stats %+v", s)
+	}
+
+	g.ResetStats()
+	if s := c.Stats(); s.Hits != 0 || s.Misses != 0 || s.Evictions != 0 {
+		t.Fatalf("expected zeroed cache stats, got %+v", s)
+	}
+	if s := g.loader.Stats(); s.Calls != 0 || s.Coalesced != 0 {
+		t.Fatalf("expected zeroed singleflight stats, got %+v", s)
+	}
+}
+
+func TestGroup_FlightTiming(t *testing.T) {
+	g := NewGroup("flight_timing_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			time.Sleep(20 * time.Millisecond)
+			return []byte("v"), nil
+		}), WithFlightTiming())
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer wg.Done()
+			g.Get("k")
+		}()
+	}
+	wg.Wait()
+
+	s := g.FlightStats()
+	if s.LeaderAvg < 20*time.Millisecond {
+		t.Fatalf("leader average should cover the load, got %v", s.LeaderAvg)
+	}
+	if s.Coalesced > 0 && s.FollowerAvg <= 0 {
+		t.Fatalf("follower wait should be recorded, got %+v", s)
+	}
+}
+
+func TestGroup_FlightForget(t *testing.T) {
+	g := &singleflight.Group{}
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	// 一个卡住且最终失败的调用
+	stuck := g.DoChan("k", func() (interface{}, error) {
+		calls.Add(1)
+		<-release
+		return nil, errors.New("peer timeout")
+	})
+	waiter := g.DoChan("k", func() (interface{}, error) {
+		t.Error("should join the in-flight call")
+		return nil, nil
+	})
+
+	// Forget 之后的调用立即重新执行，不受卡住的调用影响
+	g.Forget("k")
+	v, err := g.Do("k", func() (interface{}, error) {
+		calls.Add(1)
+		return "fresh", nil
+	})
+	if err != nil || v != "fresh" {
+		t.Fatalf("expected a fresh call after Forget, got %v, %v", v, err)
+	}
+
+	// 之前的等待者仍拿到进行中调用的结果
+	close(release)
+	for _, ch := range []<-chan singleflight.Result{stuck, waiter} {
+		if res := <-ch; res.Err == nil || !res.Shared {
+			t.Fatalf("expected the shared failure, got %+v", res)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("expected 2 calls, got %d", n)
+	}
+}
+
+func TestStatsByTag(t *testing.T) {
+	loader := callbackfunc.CallbackFunc(func(key string) ([]byte, error) {
+		return []byte(key), nil
+	})
+	a := NewGroup("tag_a", 2<<10, loader, WithTags("search", "team-x"))
+	b := NewGroup("tag_b", 2<<10, loader, WithTags("search"))
+	NewGroup("tag_c", 2<<10, loader, WithTags("ads"))
+
+	a.Get("k")
+	a.Get("k")
+	b.Get("k")
+
+	stats := StatsByTag("search")
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 groups tagged search, got %v", stats)
+	}
+	if s := stats["tag_a"]; s.Hits != 1 || s.Misses != 1 {
+		t.Fatalf("unexpected stats for tag_a: %+v", s)
+	}
+	if s := stats["tag_b"]; s.Misses != 1 {
+		t.Fatalf("unexpected stats for tag_b: %+v", s)
+	}
+	if !a.HasTag("team-x") || b.HasTag("team-x") {
+		t.Fatal("HasTag mismatch")
+	}
+}
+
+// ---------- 哈希环变更测试 ----------
+
+type prevOwnerPeers struct {
+	getter pickpeer.PeerGetter
+}
+
+func (p prevOwnerPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return nil, false }
+
+func (p prevOwnerPeers) PickPreviousOwner(key string) (pickpeer.PeerGetter, bool) {
+	return p.getter, true
+}
+
+type slowGetter struct {
+	delay time.Duration
+}
+
+func (s slowGetter) Get(in *pb.Request, out *pb.Response) error {
+	time.Sleep(s.delay)
+	out.Value = []byte("old-" + in.Key)
+	return nil
+}
+
+func TestGroup_PreviousOwner(t *testing.T) {
+	g := newTestGroup("prev_owner")
+	g.RegisterPeers(prevOwnerPeers{getter: slowGetter{}})
+
+	res, err := g.GetResult("Tom")
+	if err != nil || res.Value.String() != "old-Tom" || res.Source != SourcePeer {
+		t.Fatalf("should get from previous owner, got %q %v %v", res.Value.String(), res.Source, err)
+	}
+	if res, _ := g.GetResult("Tom"); !res.Hit {
+		t.Fatal("value from previous owner should be cached locally")
+	}
+
+	// 从旧节点取得的值同样遵循 WithTTL
+	ttl := NewGroup("prev_owner_ttl", 2<<10, nil, WithoutLoader(), WithTTL(time.Minute))
+	ttl.RegisterPeers(prevOwnerPeers{getter: slowGetter{}})
+	if _, err := ttl.Get("Tom"); err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := ttl.Stat("Tom"); !ok || st.TTL <= 0 || st.TTL > time.Minute {
+		t.Fatalf("value from previous owner should expire with WithTTL, got %+v", st)
+	}
+
+	// 旧节点超时后回源加载
+	slow := newTestGroup("prev_owner_slow")
+	slow.RegisterPeers(prevOwnerPeers{getter: slowGetter{delay: previousOwnerTimeout * 2}})
+	res, err = slow.GetResult("Jack")
+	if err != nil || res.Value.String() != "589" || res.Source != SourceLoader {
+		t.Fatalf("should fall back to loader, got %q %v %v", res.Value.String(), res.Source, err)
+	}
+}
+
+// ---------- 外部缓存测试 ----------
+
+type mapCache struct {
+	mu sync.Mutex
+	m  map[string]cache.ByteView
+}
+
+func (c *mapCache) Get(key string) (cache.ByteView, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[key]
+	return v, ok
+}
+
+func (c *mapCache) Add(key string, value cache.ByteView) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = value
+}
+
+func TestNewGroupWithCache(t *testing.T) {
+	store := &mapCache{m: make(map[string]cache.ByteView)}
+	g := NewGroupWithCache("external_cache", store, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte(db[key]), nil
+		}))
+
+	if v, err := g.Get("Tom"); err != nil || v.String() != "630" {
+		t.Fatalf("failed to get Tom: %q %v", v.String(), err)
+	}
+	if _, ok := store.m["Tom"]; !ok {
+		t.Fatal("loaded value should be stored in the external cache")
+	}
+	if res, _ := g.GetResult("Tom"); !res.Hit {
+		t.Fatal("second get should hit the external cache")
+	}
+	if g.SetNX("Tom", []byte("x")) {
+		t.Fatal("SetNX should not overwrite an existing key")
+	}
+	if err := g.Pin("Tom"); err == nil {
+		t.Fatal("Pin should fail on an external cache")
+	}
+}
+
+// ---------- 过期清理测试 ----------
+
+type expiringCache struct {
+	mapCache
+	reaped chan struct{}
+}
+
+func (c *expiringCache) RemoveExpired() int {
+	select {
+	case c.reaped <- struct{}{}:
+	default:
+	}
+	return 0
+}
+
+func TestReaper(t *testing.T) {
+	store := &expiringCache{
+		mapCache: mapCache{m: make(map[string]cache.ByteView)},
+		reaped:   make(chan struct{}, 1),
+	}
+	NewGroupWithCache("reaper_test", store, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte(key), nil
+		}))
+
+	StartReaper(time.Millisecond)
+	select {
+	case <-store.reaped:
+	case <-time.After(time.Second):
+		t.Fatal("reaper should call RemoveExpired")
+	}
+	StopReaper()
+	StopReaper()
+
+	// 停止后不应再被调用
+	select {
+	case <-store.reaped:
+	default:
+	}
+	time.Sleep(5 * time.Millisecond)
+	select {
+	case <-store.reaped:
+		t.Fatal("reaper should not run after StopReaper")
+	default:
+	}
+}
+
+// ---------- 后台任务池测试 ----------
+
+func TestWorkerPool(t *testing.T) {
+	SetPoolSize(1, 1)
+	defer SetPoolSize(defaultPoolWorkers, defaultPoolQueue)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if !submit(func() { close(started); <-release }) {
+		t.Fatal("first task should be accepted")
+	}
+	<-started
+	if !submit(func() {}) {
+		t.Fatal("second task should be queued")
+	}
+	if submit(func() {}) {
+		t.Fatal("task beyond queue capacity should be rejected")
+	}
+	if s := PoolStats(); s.Workers != 1 || s.Active != 1 || s.Queued != 1 {
+		t.Fatalf("unexpected pool stats %+v", s)
+	}
+
+	close(release)
+	StopPool()
+	if s := PoolStats(); s != (PoolStat{}) {
+		t.Fatalf("stopped pool should report zero stats, got %+v", s)
+	}
+}
+
+// ---------- 副本读取测试 ----------
+
+type replicaPeers []pickpeer.PeerGetter
+
+func (p replicaPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return nil, false }
+
+func (p replicaPeers) PickPeers(key string, n int) []pickpeer.PeerGetter {
+	if n > len(p) {
+		n = len(p)
+	}
+	return p[:n]
+}
+
+type versionGetter struct {
+	node    string
+	version int64
+	err     error
+}
+
+func (v versionGetter) Get(in *pb.Request, out *pb.Response) error {
+	if v.err != nil {
+		return v.err
+	}
+	out.Value = []byte(v.node)
+	out.Node = v.node
+	out.Version = v.version
+	return nil
+}
+
+func TestGroup_GetAllReplicas(t *testing.T) {
+	g := newTestGroup("all_replicas_test")
+	g.RegisterPeers(replicaPeers{
+		versionGetter{node: "http://a", version: 1},
+		versionGetter{err: fmt.Errorf("down")},
+		versionGetter{node: "http://c", version: 3},
+	})
+	g.Set("k", []byte("local"))
+
+	values, err := g.GetAllReplicas("k", 3)
+	if err != nil {
+		t.Fatalf("GetAllReplicas failed: %v", err)
+	}
+	// 本地 1 个 + 远程 2 个，其中一个失败被跳过
+	if len(values) != 2 {
+		t.Fatalf("expected 2 replicas, got %+v", values)
+	}
+	if values[0].Node != "" || values[0].Value.String() != "local" {
+		t.Fatalf("first replica should be local, got %+v", values[0])
+	}
+	if values[1].Node != "http://a" || values[1].Version != 1 {
+		t.Fatalf("unexpected remote replica %+v", values[1])
+	}
+}
+
+// ---------- 副本写入测试 ----------
+
+type setterPeer struct {
+	versionGetter
+	delay time.Duration
+	err   error
+	sets  *atomic.Int32
+}
+
+func (s setterPeer) Set(in *pb.Request, value []byte) error {
+	time.Sleep(s.delay)
+	if s.err != nil {
+		return s.err
+	}
+	s.sets.Add(1)
+	return nil
+}
+
+func TestGroup_SetWithReplicas(t *testing.T) {
+	var sets atomic.Int32
+	ok := setterPeer{sets: &sets}
+	down := setterPeer{err: errors.New("down"), sets: &sets}
+
+	g := newTestGroup("set_replicas_test")
+	g.RegisterPeers(replicaPeers{ok, ok})
+	if err := g.SetWithReplicas("k", []byte("v"), 3); err != nil {
+		t.Fatalf("expected 3/3 acks, got %v", err)
+	}
+	if v, _ := g.Stat("k"); v.Size != 1 || sets.Load() != 2 {
+		t.Fatalf("expected a local write and 2 remote writes, got %+v and %d", v, sets.Load())
+	}
+
+	// 远程写入失败时确认数不足
+	g2 := newTestGroup("set_replicas_few_test")
+	g2.RegisterPeers(replicaPeers{ok, down})
+	if err := g2.SetWithReplicas("k", []byte("v"), 3); err == nil || !strings.Contains(err.Error(), "2/3") {
+		t.Fatalf("expected 2/3 acks, got %v", err)
+	}
+
+	// 本地写入被拒绝时不计入确认
+	g3 := NewGroup("set_replicas_rejected_test", 2<<10, nil, WithoutLoader(), WithAdmitFraction(0.001))
+	g3.RegisterPeers(replicaPeers{ok})
+	if err := g3.SetWithReplicas("k", []byte("too large to admit"), 2); err == nil || !strings.Contains(err.Error(), "1/2") {
+		t.Fatalf("a rejected local write should not count as an ack, got %v", err)
+	}
+}
+
+func TestGroup_SetWithReplicasPoolFull(t *testing.T) {
+	SetPoolSize(1, 1)
+	defer SetPoolSize(defaultPoolWorkers, defaultPoolQueue)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	submit(func() { close(started); <-release })
+	<-started
+	submit(func() {})
+	defer close(release)
+
+	var sets atomic.Int32
+	g := newTestGroup("set_replicas_pool_full_test")
+	g.RegisterPeers(replicaPeers{setterPeer{sets: &sets}})
+	if err := g.SetWithReplicas("k", []byte("v"), 2); err == nil || !strings.Contains(err.Error(), "1/2") {
+		t.Fatalf("expected 1/2 acks when the pool is full, got %v", err)
+	}
+	if sets.Load() != 0 {
+		t.Fatal("no remote write should run when the pool is full")
+	}
+}
+
+func TestGroup_SetWithReplicasTimeout(t *testing.T) {
+	old := replicaTimeout
+	replicaTimeout = 20 * time.Millisecond
+	defer func() { replicaTimeout = old }()
+
+	var sets atomic.Int32
+	g := newTestGroup("set_replicas_timeout_test")
+	g.RegisterPeers(replicaPeers{setterPeer{delay: 200 * time.Millisecond, sets: &sets}})
+	start := time.Now()
+	err := g.SetWithReplicas("k", []byte("v"), 2)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if time.Since(start) > 150*time.Millisecond {
+		t.Fatal("SetWithReplicas should not wait for slow replicas past replicaTimeout")
+	}
+}
+
+// ---------- 旧值兜底测试 ----------
+
+// expiredCache 模拟条目已过期：Get 未命中，但 PeekStale 仍能读到旧值
+type expiredCache struct {
+	mapCache
+}
+
+func (c *expiredCache) Get(key string) (cache.ByteView, bool) { return cache.ByteView{}, false }
+
+func (c *expiredCache) PeekStale(key string) (cache.ByteView, bool) {
+	return c.mapCache.Get(key)
+}
+
+func TestGroup_ServeStaleOnError(t *testing.T) {
+	store := &expiredCache{mapCache{m: make(map[string]cache.ByteView)}}
+	store.Add("Tom", cache.NewByteView([]byte("old")))
+	sourceDown := callbackfunc.CallbackFunc(func(key string) ([]byte, error) {
+		return nil, fmt.Errorf("source down")
+	})
+
+	g := NewGroupWithCache("serve_stale_test", store, sourceDown, WithServeStaleOnError())
+	g.RegisterPeers(flakyPeers{&flakyGetter{failures: 100}})
+
+	res, err := g.GetResult("Tom")
+	if err != nil || !res.Stale || res.Value.String() != "old" {
+		t.Fatalf("should serve stale value, got %+v %v", res, err)
+	}
+	if _, err := g.GetResult("Jack"); err == nil {
+		t.Fatal("should still fail when no stale value exists")
+	}
+
+	strict := NewGroupWithCache("serve_stale_off_test", store, sourceDown)
+	if _, err := strict.GetResult("Tom"); err == nil {
+		t.Fatal("should fail when the option is off")
+	}
+}
+
+// ---------- GetMultiContext 测试 ----------
+
+func TestGroup_GetMultiContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	g := NewGroup("get_multi_ctx_test", 0, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			switch key {
+			case "slow":
+				<-release
+			case "bad":
+				return nil, fmt.Errorf("%s not exist", key)
+			}
+			return []byte("v-" + key), nil
+		}))
+	g.Set("cached", []byte("v-cached"))
+
+	// 已缓存的 key 即使 ctx 已过期也会返回
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	values, err := g.GetMultiContext(expired, []string{"cached", "fast"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(values) != 1 || values["cached"].String() != "v-cached" {
+		t.Fatalf("expected only the cached key, got %v", values)
+	}
+
+	// 超时时返回已取到的部分
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	values, err = g.GetMultiContext(ctx, []string{"cached", "fast", "slow", "fast"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if len(values) != 2 || values["fast"].String() != "v-fast" {
+		t.Fatalf("expected cached and fast keys, got %v", values)
+	}
+
+	// 加载失败的 key 不在结果中，错误被返回
+	values, err = g.GetMultiContext(context.Background(), []string{"fast", "bad"})
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Fatalf("expected error for bad key, got %v", err)
+	}
+	if _, ok := values["bad"]; ok || values["fast"].String() != "v-fast" {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
+
+func TestGroup_GetMultiLookup(t *testing.T) {
+	var loads atomic.Int32
+	g := NewGroup("get_multi_lookup_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			if key == "missing" {
+				return nil, ErrNotFound
+			}
+			return []byte("v-" + key), nil
+		}), WithNegativeTTL(time.Minute))
+	g.Set("cached", []byte("v-cached"))
+	g.Get("missing")
+	before := g.Stats()
+
+	// 与 GetContext 一样计入统计，命中负缓存的 key 不再回源
+	values, err := g.GetMultiContext(context.Background(), []string{"cached", "missing", "new", "cached"})
+	if !errors.Is(err, ErrNotFound) || len(values) != 2 || values["new"].String() != "v-new" {
+		t.Fatalf("unexpected result %v, %v", values, err)
+	}
+	st := g.Stats()
+	if st.Gets-before.Gets != 3 || st.LocalHits-before.LocalHits != 2 {
+		t.Fatalf("expected 3 gets and 2 local hits, got %+v", st)
+	}
+	if loads.Load() != 2 {
+		t.Fatalf("negatively cached key should not be reloaded, got %d loads", loads.Load())
+	}
+}
+
+// ---------- Warm 测试 ----------
+
+func TestGroup_Warm(t *testing.T) {
+	var running, peak, loads atomic.Int32
+	g := NewGroup("warm_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			if key == "bad" {
+				return nil, ErrNotFound
+			}
+			return []byte("v-" + key), nil
+		}))
+
+	err := g.Warm([]string{"a", "b", "c", "a", "d", "bad"}, 2)
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "bad") {
+		t.Fatalf("expected per-key error for bad, got %v", err)
+	}
+	if loads.Load() != 5 {
+		t.Fatalf("duplicate keys should load once, got %d loads", loads.Load())
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("concurrency should be limited to 2, got %d", peak.Load())
+	}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if v, ok := g.cache.Get(key); !ok || v.String() != "v-"+key {
+			t.Fatalf("%s should be warmed, got %q", key, v.String())
+		}
+	}
+	if err := g.Warm([]string{"a", "b"}, 0); err != nil || loads.Load() != 5 {
+		t.Fatalf("warm keys should hit the cache, got %v after %d loads", err, loads.Load())
+	}
+}
+
+// ---------- 负缓存测试 ----------
+
+func TestGroup_NegativeTTL(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		store = map[string]string{}
+		loads int
+	)
+	g := NewGroup("negative_ttl_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			loads++
+			if v, ok := store[key]; ok {
+				return []byte(v), nil
+			}
+			return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}), WithTTL(time.Hour), WithNegativeTTL(50*time.Millisecond))
+
+	if _, err := g.Get("new"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// key 在缓存未命中之后创建，负缓存有效期内仍返回未找到且不回源
+	mu.Lock()
+	store["new"] = "created"
+	mu.Unlock()
+	if _, err := g.Get("new"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected cached ErrNotFound, got %v", err)
+	}
+	if loads != 1 {
+		t.Fatalf("expected 1 load, got %d", loads)
+	}
+
+	// 负缓存过期后可以取到新建的 key，正缓存的 TTL 不受影响
+	time.Sleep(60 * time.Millisecond)
+	v, err := g.Get("new")
+	if err != nil || v.String() != "created" {
+		t.Fatalf("expected 'created' after negative TTL, got %q, %v", v.String(), err)
+	}
+	if st, _ := g.Stat("new"); st.TTL <= time.Minute {
+		t.Fatalf("positive TTL should be independent, got %v", st.TTL)
+	}
+
+	// 负缓存命中与真正查询数据源的未找到可以区分
+	g.Get("other")
+	res, err := g.GetResult("other")
+	if !errors.Is(err, ErrNotFound) || !res.Negative || !res.Hit {
+		t.Fatalf("expected a negative cache hit, got %+v, %v", res, err)
+	}
+	g.Set("other", []byte("set"))
+	if v, err := g.Get("other"); err != nil || v.String() != "set" {
+		t.Fatalf("Set should clear the negative entry, got %q, %v", v.String(), err)
+	}
+
+	// 其他错误不会被负缓存
+	g2 := NewGroup("negative_other_err_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			loads++
+			return nil, errors.New("db down")
+		}), WithNegativeTTL(0))
+	g2.Get("k")
+	g2.Get("k")
+	if loads != 5 {
+		t.Fatalf("non-not-found errors should not be cached, got %d loads", loads)
+	}
+}
+
+// ---------- GetMulti 测试 ----------
+
+type countingPeers struct {
+	calls *atomic.Int32
+}
+
+func (p countingPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return p, true }
+
+func (p countingPeers) Get(in *pb.Request, out *pb.Response) error {
+	p.calls.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	if in.GetKey() == "missing" {
+		return fmt.Errorf("%s: %w", in.GetKey(), ErrNotFound)
+	}
+	out.Value = []byte("peer-" + in.GetKey())
+	return nil
+}
+
+func TestGroup_GetMulti(t *testing.T) {
+	var calls atomic.Int32
+	g := NewGroup("get_multi_test", 2<<10, nil, WithoutLoader())
+	g.RegisterPeers(countingPeers{&calls})
+	g.Set("cached", []byte("local"))
+
+	keys := []string{"a", "b", "c", "d", "a", "cached", "missing"}
+	start := time.Now()
+	values, err := g.GetMulti(keys)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected the failed key's error, got %v", err)
+	}
+	if len(values) != 5 || values["a"].String() != "peer-a" || values["cached"].String() != "local" {
+		t.Fatalf("unexpected values %v", values)
+	}
+	// 重复 key 只取一次，缓存命中不访问远程节点
+	if n := calls.Load(); n != 5 {
+		t.Fatalf("expected 5 peer calls, got %d", n)
+	}
+	// 远程请求并发进行
+	if elapsed > 80*time.Millisecond {
+		t.Fatalf("peer requests should run concurrently, took %v", elapsed)
+	}
+}
+
+func TestGroup_GetMulti_Concurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	g := NewGroup("get_multi_concurrency_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return []byte("value-" + key), nil
+		}), WithMultiConcurrency(3))
+
+	keys := make([]string, 20)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k%d", i)
+	}
+	values, err := g.GetMulti(keys)
+	if err != nil || len(values) != len(keys) {
+		t.Fatalf("expected %d values, got %d, %v", len(keys), len(values), err)
+	}
+	if n := peak.Load(); n > 3 {
+		t.Fatalf("expected at most 3 concurrent loads, got %d", n)
+	}
+}
+
+// compressibleJSON 返回约 16KB 的 JSON 文档，压缩率与实际业务数据相近
+func compressibleJSON() []byte {
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id":%d,"name":"user-%d","email":"user-%d@example.com","active":true}`, i, i, i)
+	}
+	b.WriteString("]")
+	return []byte(b.String())
+}
+
+func TestGroup_CompressionThreshold(t *testing.T) {
+	doc := compressibleJSON()
+	g := NewGroup("compression_test", 1<<20, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if key == "small" {
+				return []byte("tiny"), nil
+			}
+			return doc, nil
+		}), WithCompressionThreshold(1024))
+
+	for _, key := range []string{"doc", "doc"} {
+		bv, err := g.Get(key)
+		if err != nil || bv.String() != string(doc) {
+			t.Fatalf("Get should return the original value, got err %v", err)
+		}
+	}
+	c, _ := g.local()
+	stored, _ := c.Get("doc")
+	if stored.Encoding() != cache.EncodingGzip || stored.Len()*5 > len(doc) {
+		t.Fatalf("large value should be stored compressed, encoding %q, %d of %d bytes", stored.Encoding(), stored.Len(), len(doc))
+	}
+
+	g.Get("small")
+	if stored, _ := c.Get("small"); stored.Encoding() != "" {
+		t.Fatal("values below the threshold should be stored as is")
+	}
+}
+
+// BenchmarkGroup_Compression 对比压缩存储对命中延迟与内存占用的影响
+func BenchmarkGroup_Compression(b *testing.B) {
+	doc := compressibleJSON()
+	for _, threshold := range []int{0, 1024} {
+		b.Run(fmt.Sprintf("threshold=%d", threshold), func(b *testing.B) {
+			g := NewGroup(fmt.Sprintf("compression_bench_%d", threshold), 1<<20, callbackfunc.CallbackFunc(
+				func(key string) ([]byte, error) {
+					return doc, nil
+				}), WithCompressionThreshold(threshold))
+			g.Get("doc")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				bv, _ := g.Get("doc")
+				bv.ByteSlice()
+			}
+			c, _ := g.local()
+			b.ReportMetric(float64(c.Stats().Bytes), "stored-bytes")
+		})
+	}
+}
+
+// ctxPeer 记录收到的 ctx 是否带有截止时间
+type ctxPeer struct{ deadline chan bool }
+
+func (p ctxPeer) PickPeer(key string) (pickpeer.PeerGetter, bool) { return p, true }
+
+func (p ctxPeer) Get(in *pb.Request, out *pb.Response) error {
+	return p.GetContext(context.Background(), in, out)
+}
+
+func (p ctxPeer) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	_, ok := ctx.Deadline()
+	p.deadline <- ok
+	out.Value = []byte("peer-" + in.GetKey())
+	return nil
+}
+
+func TestGroup_GetContext(t *testing.T) {
+	var loads atomic.Int32
+	g := NewGroupContext("get_context_test", 2<<10, callbackfunc.CallbackFuncCtx(
+		func(ctx context.Context, key string) ([]byte, error) {
+			loads.Add(1)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(50 * time.Millisecond):
+				return []byte("value-" + key), nil
+			}
+		}))
+
+	// 超时后回调立即放弃，结果不写入缓存
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := g.GetContext(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("callback should observe the deadline, took %v", elapsed)
+	}
+	if v, err := g.Get("k"); err != nil || v.String() != "value-k" {
+		t.Fatalf("Get should load with a background context, got %q, %v", v.String(), err)
+	}
+	if n := loads.Load(); n != 2 {
+		t.Fatalf("expected 2 loads, got %d", n)
+	}
+
+	// 已取消的 ctx 不影响缓存命中
+	cancel()
+	if v, err := g.GetContext(ctx, "k"); err != nil || v.String() != "value-k" {
+		t.Fatalf("cache hit should ignore ctx, got %q, %v", v.String(), err)
+	}
+
+	// ctx 传给支持 GetContext 的远程节点
+	peer := ctxPeer{make(chan bool, 1)}
+	pg := NewGroup("get_context_peer_test", 2<<10, nil, WithoutLoader())
+	pg.RegisterPeers(peer)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if v, err := pg.GetContext(ctx, "k"); err != nil || v.String() != "peer-k" {
+		t.Fatalf("unexpected peer result %q, %v", v.String(), err)
+	}
+	if !<-peer.deadline {
+		t.Fatal("peer should receive the caller's ctx")
+	}
+}
+
+func TestGroup_GetContextForwarded(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{})
+	g := NewGroupContext("get_context_forwarded_test", 2<<10, callbackfunc.CallbackFuncCtx(
+		func(ctx context.Context, key string) ([]byte, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return []byte("local-" + key), nil
+		}))
+	g.RegisterPeers(countingPeers{&calls})
+
+	// 转发来的请求在本节点回源，加载期间本节点发起的请求不能加入它而跳过 PickPeer
+	done := make(chan string, 1)
+	go func() {
+		v, _ := g.GetContext(Forwarded(context.Background()), "k")
+		done <- v.String()
+	}()
+	<-started
+	if v, err := g.GetContext(context.Background(), "k"); err != nil || v.String() != "peer-k" {
+		t.Fatalf("local request should go to the peer, got %q, %v", v.String(), err)
+	}
+	if v := <-done; v != "local-k" {
+		t.Fatalf("forwarded request should load locally, got %q", v)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected 1 peer call, got %d", n)
+	}
+}
+
+func TestGroup_GetContextCancel(t *testing.T) {
+	var loads atomic.Int32
+	release := make(chan struct{})
+	canceled := make(chan error, 1)
+	g := NewGroupContext("get_context_cancel_test", 2<<10, callbackfunc.CallbackFuncCtx(
+		func(ctx context.Context, key string) ([]byte, error) {
+			loads.Add(1)
+			select {
+			case <-release:
+				return []byte("value-" + key), nil
+			case <-ctx.Done():
+				canceled <- ctx.Err()
+				return nil, ctx.Err()
+			}
+		}))
+
+	// 先到的调用方放弃等待后立即返回，共享的加载继续为其他调用方进行
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.GetContext(ctx, "k")
+		done <- err
+	}()
+	for loads.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	other := make(chan cache.ByteView, 1)
+	go func() {
+		v, _ := g.Get("k")
+		other <- v
+	}()
+	for g.FlightStats().Coalesced == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case err := <-canceled:
+		t.Fatalf("shared load should not be canceled while others wait, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if v := <-other; v.String() != "value-k" || loads.Load() != 1 {
+		t.Fatalf("other caller should get the shared load, got %q after %d loads", v.String(), loads.Load())
+	}
+
+	// 所有调用方都放弃后加载被取消
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g2 := NewGroupContext("get_context_cancel_all_test", 2<<10, callbackfunc.CallbackFuncCtx(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil, ctx.Err()
+		}))
+	if _, err := g2.GetContext(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("load should be canceled once every caller gave up")
+	}
+}
+
+func TestGroup_PeerNotFound(t *testing.T) {
+	var calls atomic.Int32
+	var loads atomic.Int32
+	g := NewGroup("peer_not_found_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			return []byte("local-" + key), nil
+		}), WithNegativeTTL(time.Minute))
+	g.RegisterPeers(countingPeers{&calls})
+
+	// 归属节点确认 key 不存在时不再回源，并写入负缓存
+	for i := 0; i < 2; i++ {
+		if _, err := g.Get("missing"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected ErrNotFound from the peer, got %v", err)
+		}
+	}
+	if loads.Load() != 0 || calls.Load() != 1 {
+		t.Fatalf("expected 1 peer call and no local load, got %d and %d", calls.Load(), loads.Load())
+	}
+}
+
+func TestGroup_PeerErrorContext(t *testing.T) {
+	var calls atomic.Int32
+	g := NewGroup("peer_error_context_test", 2<<10, nil, WithoutLoader())
+	_, err := g.getFromPeer(context.Background(), countingPeers{&calls}, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("wrapped error should still match ErrNotFound, got %v", err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `group "peer_error_context_test"`) || !strings.Contains(msg, `key "missing"`) {
+		t.Fatalf("error should name the group and key, got %q", msg)
+	}
+}
+
+func TestGroup_OnEvicted(t *testing.T) {
+	index := map[string]bool{}
+	var g *Group
+	g = NewGroup("on_evicted_test", 10, nil, WithoutLoader(), WithOnEvicted(func(key string, value cache.ByteView) {
+		// 回调中可以安全地访问 Group
+		if _, ok := g.Stat(key); ok {
+			t.Errorf("%s should be gone when its callback runs", key)
+		}
+		delete(index, key)
+	}))
+	for _, key := range []string{"k1", "k2", "k3"} {
+		g.Set(key, []byte("123"))
+		index[key] = true
+	}
+	if index["k1"] || !index["k2"] || !index["k3"] {
+		t.Fatalf("evicted key should be pruned from the index, got %v", index)
+	}
+}
+
+// permanentError 模拟重试也无法恢复的远程错误，如 404
+type permanentError struct{}
+
+func (permanentError) Error() string   { return "permanent" }
+func (permanentError) Temporary() bool { return false }
+
+type permanentPeers struct{ calls *atomic.Int32 }
+
+func (p permanentPeers) PickPeer(key string) (pickpeer.PeerGetter, bool) { return p, true }
+
+func (p permanentPeers) Get(in *pb.Request, out *pb.Response) error {
+	p.calls.Add(1)
+	return permanentError{}
+}
+
+func TestGroup_RetryPolicy(t *testing.T) {
+	source := callbackfunc.CallbackFunc(func(key string) ([]byte, error) {
+		return []byte("source"), nil
+	})
+
+	// 指数退避：两次重试至少等待 5ms/2 + 10ms/2
+	getter := &flakyGetter{failures: 2}
+	g := NewGroup("retry_policy_test", 2<<10, source)
+	g.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: 5 * time.Millisecond})
+	g.RegisterPeers(flakyPeers{getter})
+	start := time.Now()
+	if res, _ := g.GetResult("k"); res.Source != SourcePeer || getter.calls != 3 {
+		t.Fatalf("should succeed on the third attempt, got %+v after %d calls", res, getter.calls)
+	}
+	if elapsed := time.Since(start); elapsed < 7*time.Millisecond {
+		t.Fatalf("retries should back off, took %v", elapsed)
+	}
+
+	// 不可重试的错误直接回源
+	var calls atomic.Int32
+	g = NewGroup("retry_permanent_test", 2<<10, source, WithRetryPolicy(RetryPolicy{MaxAttempts: 5}))
+	g.RegisterPeers(permanentPeers{&calls})
+	if res, _ := g.GetResult("k"); res.Source != SourceLoader || calls.Load() != 1 {
+		t.Fatalf("permanent errors should not be retried, got %+v after %d calls", res, calls.Load())
+	}
+
+	// 等待会超过截止时间时放弃重试
+	getter = &flakyGetter{failures: 1}
+	g = NewGroup("retry_deadline_test", 2<<10, source, WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second}))
+	g.RegisterPeers(flakyPeers{getter})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if v, err := g.GetContext(ctx, "k"); err != nil || v.String() != "source" || getter.calls != 1 {
+		t.Fatalf("should fall back to source without waiting, got %q, %v after %d calls", v.String(), err, getter.calls)
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Fatalf("should not wait past the deadline, took %v", elapsed)
+	}
+}
@@ -0,0 +1,128 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	cache "geecache/Cache"
+	"runtime"
+	"sync"
+)
+
+// GetMulti 批量获取 keys 对应的值：重复的 key 只取一次，本地缓存命中的 key 不访问网络，
+// 其余 key 并发地向远程节点或数据源加载，同时加载的 key 数受 WithMultiConcurrency 限制。单个 key 失败不影响其他 key，
+// 返回成功部分的映射与合并后的错误（可用 errors.Is 逐一检查）。
+func (g *Group) GetMulti(keys []string) (map[string]cache.ByteView, error) {
+	return g.GetMultiContext(context.Background(), keys)
+}
+
+// GetMultiContext 与 GetMulti 相同，并支持截止时间。
+// 已在本地缓存中的 key 总会被返回，即使 ctx 已经过期；未命中的 key 并发加载，
+// ctx 在加载完成前结束时立即返回已取到的部分与 ctx.Err()，ctx 同时传给各 key 的加载，见 GetContext。
+// ctx 未结束时，加载失败（包括命中负缓存）的 key 不出现在结果中，其错误合并后返回。
+// 每个 key 的本地查找与 GetContext 相同：计入访问统计，并按 WithSoftMaxAge 触发后台刷新。
+func (g *Group) GetMultiContext(ctx context.Context, keys []string) (map[string]cache.ByteView, error) {
+	values := make(map[string]cache.ByteView, len(keys))
+	var (
+		missing []string
+		errs    []error
+	)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		res, ok, err := g.lookup(key)
+		switch {
+		case !ok:
+			missing = append(missing, key)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		default:
+			values[key] = res.Value
+		}
+	}
+	if len(missing) == 0 {
+		return values, errors.Join(errs...)
+	}
+	if err := ctx.Err(); err != nil {
+		return values, err
+	}
+
+	type loaded struct {
+		key   string
+		value cache.ByteView
+		err   error
+	}
+	queue := make(chan string, len(missing))
+	for _, key := range missing {
+		queue <- key
+	}
+	close(queue)
+	// 缓冲足够大，放弃等待后加载 goroutine 仍能写入并退出；ctx 结束后剩余的 key 由 loadResult 立即返回
+	ch := make(chan loaded, len(missing))
+	workers := g.multiConcurrency
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0) * 4
+	}
+	for i := 0; i < min(workers, len(missing)); i++ {
+		go func() {
+			for key := range queue {
+				res, err := g.loadResult(ctx, key)
+				ch <- loaded{key, res.Value, err}
+			}
+		}()
+	}
+
+	for pending := len(missing); pending > 0; pending-- {
+		select {
+		case r := <-ch:
+			if r.err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", r.key, r.err))
+				continue
+			}
+			values[r.key] = r.value
+		case <-ctx.Done():
+			return values, ctx.Err()
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// Warm 以最多 concurrency 个并发预热 keys：每个 key 都走 Get 的正常路径（远程节点或数据源），
+// 重复的 key 只加载一次，与同时到达的请求经由 singleflight 合并。concurrency < 1 时按 1 处理。
+// 全部完成后返回，单个 key 失败不影响其他 key，各 key 的错误合并后返回（可用 errors.Is 逐一检查）。
+func (g *Group) Warm(keys []string, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var (
+		wg   sync.WaitGroup
+		emu  sync.Mutex
+		errs []error
+	)
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err := g.Get(key); err != nil {
+				emu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				emu.Unlock()
+			}
+		}(key)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,161 @@
+package group
+
+import (
+	cache "geecache/Cache"
+	"time"
+)
+
+// Option 用于在 NewGroup 时配置 Group
+type Option func(*Group)
+
+// WithoutLoader 允许创建没有回调函数的 Group。
+// 这类 Group 的数据只能通过 Set 或副本推送写入，本地与远程节点都未命中时 Get 返回 ErrCacheMiss。
+func WithoutLoader() Option {
+	return func(g *Group) {
+		g.noLoader = true
+	}
+}
+
+// WithAdmitFraction 设置本地缓存的准入比例，超过容量该比例的值只返回给调用方而不缓存，见 cache.Cache.AdmitFraction。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithAdmitFraction(fraction float64) Option {
+	return func(g *Group) {
+		if c, ok := g.local(); ok {
+			c.AdmitFraction = fraction
+		}
+	}
+}
+
+// WithMaxEntries 限制本地缓存的条目数，超出时按淘汰策略淘汰，见 cache.Cache.MaxEntries。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithMaxEntries(n int) Option {
+	return func(g *Group) {
+		if c, ok := g.local(); ok {
+			c.MaxEntries = n
+		}
+	}
+}
+
+// WithOverflowPolicy 设置本地缓存已满时的写入策略，见 cache.OverflowPolicy。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithOverflowPolicy(policy cache.OverflowPolicy) Option {
+	return func(g *Group) {
+		if c, ok := g.local(); ok {
+			c.Overflow = policy
+		}
+	}
+}
+
+// WithEvictionPolicy 设置本地缓存的淘汰策略，见 cache.Policy。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithEvictionPolicy(policy cache.Policy) Option {
+	return func(g *Group) {
+		if c, ok := g.local(); ok {
+			c.Policy = policy
+		}
+	}
+}
+
+// WithCompressionThreshold 使不小于 threshold 字节的值以 gzip 压缩的形式存入本地缓存，
+// 读取时透明解压，以 CPU 换内存，适合 JSON 等压缩率高的大值。threshold 小于等于 0 时不压缩。
+// 远程节点以裸传输模式请求且接受 gzip 时，Serve 直接发送压缩数据，不会解压后再压缩。
+func WithCompressionThreshold(threshold int) Option {
+	return func(g *Group) {
+		g.compressThreshold = threshold
+	}
+}
+
+// WithOnEvicted 设置本地缓存的淘汰回调，见 cache.Cache.OnEvicted，可用于维护缓存 key 的二级索引。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithOnEvicted(fn func(key string, value cache.ByteView)) Option {
+	return func(g *Group) {
+		if c, ok := g.local(); ok {
+			c.OnEvicted = fn
+		}
+	}
+}
+
+// WithPeerRetries 设置从远程节点获取失败后立即重试的次数，全部失败后才回源加载。默认 0 不重试。
+// 需要退避等待时使用 WithRetryPolicy。
+func WithPeerRetries(n int) Option {
+	return WithRetryPolicy(RetryPolicy{MaxAttempts: n + 1})
+}
+
+// WithRetryPolicy 在创建时设置重试策略，见 Group.SetRetryPolicy
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(g *Group) {
+		g.SetRetryPolicy(p)
+	}
+}
+
+// WithServeStaleOnError 开启后，远程节点与回调函数都失败时，若本地缓存中仍有该 key 的旧值
+// （包括已过期但尚未清理的），返回旧值而不是错误。本地缓存需实现 cache.StaleReader；
+// 默认的本地缓存会因此保留过期条目直到被清理，见 cache.Cache.KeepExpired。
+func WithServeStaleOnError() Option {
+	return func(g *Group) {
+		g.serveStale = true
+		if c, ok := g.local(); ok {
+			c.KeepExpired = true
+		}
+	}
+}
+
+// WithTags 为 Group 添加标签，用于按功能或团队归类，见 StatsByTag
+func WithTags(tags ...string) Option {
+	return func(g *Group) {
+		g.tags = append(g.tags, tags...)
+	}
+}
+
+// WithFlightTiming 开启请求合并的耗时统计，见 Group.FlightStats
+func WithFlightTiming() Option {
+	return func(g *Group) {
+		g.loader.Timing = true
+	}
+}
+
+// WithSoftMaxAge 设置软过期时间：命中的本地缓存值存在时间超过 maxAge 时，
+// 仍立即返回当前值，同时在后台经 singleflight 重新加载。与硬过期不同，值不会因此失效。
+func WithSoftMaxAge(maxAge time.Duration) Option {
+	return func(g *Group) {
+		g.softMaxAge = maxAge
+	}
+}
+
+// WithMultiConcurrency 限制 GetMulti 与 GetMultiContext 同时加载的未命中 key 数，
+// 避免一次传入大量 key 时 goroutine 与对远程节点、数据源的并发请求失控。n 不大于 0 时使用默认的 GOMAXPROCS*4。
+func WithMultiConcurrency(n int) Option {
+	return func(g *Group) {
+		g.multiConcurrency = n
+	}
+}
+
+// WithTTL 设置回源加载的值在本地缓存中的有效期，过期后下次 Get 重新加载。
+// 使用 NewGroupWithCache 提供的外部缓存时无效。
+func WithTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		g.ttl = ttl
+	}
+}
+
+// WithNegativeTTL 开启负缓存：回调函数返回 ErrNotFound 的 key 在 ttl 内再次 Get 时直接返回 ErrNotFound，
+// 不再调用回调函数。ttl 与 WithTTL 相互独立，不大于 0 时使用默认的 5 秒。
+// 负缓存让不存在的 key 不会反复压到数据源上，较短的 ttl 让新建的 key 尽快可见。
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(g *Group) {
+		if ttl <= 0 {
+			ttl = defaultNegativeTTL
+		}
+		g.negative = cache.NewCache(negativeCacheBytes, nil)
+		g.negativeTTL = ttl
+	}
+}
+
+// WithOnMiss 设置未命中回调：本地缓存与远程节点都没有 key、需要回源加载时，
+// 以 sampleRate（0~1）的概率调用 fn。fn 在锁外同步调用，不应阻塞。
+func WithOnMiss(fn func(key string), sampleRate float64) Option {
+	return func(g *Group) {
+		g.onMiss = fn
+		g.missSampleRate = sampleRate
+	}
+}
@@ -0,0 +1,104 @@
+package group
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// 默认的共享后台任务池大小
+const (
+	defaultPoolWorkers = 64
+	defaultPoolQueue   = 1024
+)
+
+// workerPool 是所有 Group 共享的有界后台任务池，副本推送、后台刷新等异步工作都经由它执行，
+// 避免流量突增时 goroutine 数量失控
+type workerPool struct {
+	tasks  chan func()
+	active atomic.Int64
+	wg     sync.WaitGroup
+}
+
+var pool = struct {
+	mu      sync.Mutex
+	p       *workerPool
+	workers int
+	queue   int
+}{workers: defaultPoolWorkers, queue: defaultPoolQueue}
+
+// PoolStat 是共享后台任务池的运行状态
+type PoolStat struct {
+	Workers int // 工作 goroutine 数，任务池未启动时为 0
+	Active  int // 正在执行任务的工作 goroutine 数
+	Queued  int // 等待执行的任务数
+}
+
+// SetPoolSize 设置共享后台任务池的工作 goroutine 数与队列长度。
+// 已在运行的任务池会先等待已提交的任务执行完毕再关闭，下一次提交任务时按新大小启动。
+func SetPoolSize(workers, queue int) {
+	if workers < 1 {
+		workers = 1
+	}
+	StopPool()
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.workers, pool.queue = workers, queue
+}
+
+// StopPool 关闭共享后台任务池并等待已提交的任务执行完毕，之后再提交任务会重新启动任务池
+func StopPool() {
+	pool.mu.Lock()
+	p := pool.p
+	pool.p = nil
+	pool.mu.Unlock()
+	if p == nil {
+		return
+	}
+	close(p.tasks)
+	p.wg.Wait()
+}
+
+// PoolStats 返回共享后台任务池的当前状态
+func PoolStats() PoolStat {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.p == nil {
+		return PoolStat{}
+	}
+	return PoolStat{
+		Workers: pool.workers,
+		Active:  int(pool.p.active.Load()),
+		Queued:  len(pool.p.tasks),
+	}
+}
+
+// submit 将 fn 提交到共享后台任务池，队列已满时不阻塞并返回 false
+func submit(fn func()) bool {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if pool.p == nil {
+		pool.p = startPool(pool.workers, pool.queue)
+	}
+	select {
+	case pool.p.tasks <- fn:
+		return true
+	default:
+		return false
+	}
+}
+
+func startPool(workers, queue int) *workerPool {
+	p := &workerPool{tasks: make(chan func(), queue)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for fn := range p.tasks {
+				p.active.Add(1)
+				fn()
+				p.active.Add(-1)
+			}
+		}()
+	}
+	return p
+}
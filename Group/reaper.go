@@ -0,0 +1,67 @@
+package group
+
+import (
+	cache "geecache/Cache"
+	"sync"
+	"time"
+)
+
+// reaper 是所有 Group 共享的过期清理 goroutine，避免每个 Group 各起一个
+var reaper struct {
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartReaper 启动共享的过期清理 goroutine，每隔 interval 对所有已注册且本地缓存实现了
+// cache.Expirer 的 Group 清理一次过期条目。已在运行时先停止旧的再以新的间隔启动。
+func StartReaper(interval time.Duration) {
+	StopReaper()
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	stop, done := make(chan struct{}), make(chan struct{})
+	reaper.stop, reaper.done = stop, done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				reapAll()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopReaper 停止共享的过期清理 goroutine 并等待其退出，未启动时直接返回
+func StopReaper() {
+	reaper.mu.Lock()
+	defer reaper.mu.Unlock()
+	if reaper.stop == nil {
+		return
+	}
+	close(reaper.stop)
+	<-reaper.done
+	reaper.stop, reaper.done = nil, nil
+}
+
+// reapAll 清理所有已注册 Group 的过期条目，返回删除的条目总数
+func reapAll() int {
+	mu.RLock()
+	expirers := make([]cache.Expirer, 0, len(groups))
+	for _, g := range groups {
+		if e, ok := g.cache.(cache.Expirer); ok {
+			expirers = append(expirers, e)
+		}
+	}
+	mu.RUnlock()
+
+	removed := 0
+	for _, e := range expirers {
+		removed += e.RemoveExpired()
+	}
+	return removed
+}
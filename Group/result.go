@@ -0,0 +1,66 @@
+package group
+
+import (
+	cache "geecache/Cache"
+	"time"
+)
+
+// Source 表示一次 Get 的数据来源
+type Source int
+
+const (
+	SourceCache  Source = iota // 本地缓存命中
+	SourcePeer                 // 从远程节点获取
+	SourceLoader               // 调用回调函数从数据源加载
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceCache:
+		return "cache"
+	case SourcePeer:
+		return "peer"
+	case SourceLoader:
+		return "loader"
+	}
+	return "unknown"
+}
+
+// Result 描述一次缓存访问的完整结果
+type Result struct {
+	Value  cache.ByteView
+	Meta   cache.Meta // 值的元数据，来自远程节点时由其 Serve 填充
+	Hit    bool       // 是否命中本地缓存
+	Source Source     // 数据来源
+	Shared bool       // 是否与并发的相同请求共享了同一次加载
+	Stale  bool       // 是否为远程节点与数据源都失败后返回的旧值，见 WithServeStaleOnError
+
+	// Negative 为 true 表示命中负缓存：key 不久前被数据源确认不存在，本次直接返回 ErrNotFound 而未回源；
+	// 为 false 且返回 ErrNotFound 时表示本次确实查询了数据源。见 WithNegativeTTL
+	Negative bool
+}
+
+// Stats 是 Group 的访问计数快照，见 Group.Stats
+type Stats struct {
+	Gets       int64 // Get 的总次数
+	LocalHits  int64 // 本地缓存命中次数（包括负缓存命中）
+	PeerHits   int64 // 从远程节点取到值的次数
+	Loads      int64 // 调用回调函数回源加载的次数
+	LoadErrors int64 // 回调函数返回错误的次数
+	Coalesced  int64 // 与并发的相同请求合并、共享加载结果的次数
+}
+
+// EntryStat 描述本地缓存中一个条目的元数据，不包含值本身
+type EntryStat struct {
+	Size      int           // 值的字节数
+	CreatedAt time.Time     // 值被加载或写入的时间
+	TTL       time.Duration // 剩余有效期，0 表示不会过期
+	Version   int64         // 值的版本号，见 cache.Meta
+}
+
+// ReplicaValue 是 key 在某一个副本上的值
+type ReplicaValue struct {
+	Value   cache.ByteView
+	Version int64  // 值的版本号，见 cache.Meta
+	Node    string // 提供该值的节点地址，本地副本为空
+}
@@ -0,0 +1,90 @@
+package group
+
+import (
+	"context"
+	"errors"
+	cache "geecache/Cache"
+	pickpeer "geecache/PickPeer"
+	"log"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy 决定从远程节点获取失败后如何重试，全部失败后才回源加载
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数（含第一次），小于等于 1 表示不重试
+	BaseDelay   time.Duration // 第一次重试前的平均等待时间，之后每次翻倍；0 表示立即重试
+	MaxDelay    time.Duration // 单次等待时间的上限，0 表示不限制
+}
+
+// maxBackoffShift 限制指数退避的翻倍次数，避免移位溢出
+const maxBackoffShift = 30
+
+// delay 返回第 n 次重试（从 1 开始）前的等待时间：按指数退避计算后在 [d/2, d] 内随机抖动，
+// 避免多个节点对同一个恢复中的节点同时重试
+func (p RetryPolicy) delay(n int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	shift := n - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	d := p.BaseDelay << shift
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// SetRetryPolicy 设置从远程节点获取失败后的重试策略，对之后开始的加载生效，可与 Get 并发调用。
+// ErrNotFound、ErrCacheMiss 与 ctx 结束不会重试，错误实现了 Temporary() bool 且返回 false 时
+// （如 httpclient.Error 的 4xx）也不会重试；下一次等待会超过 ctx 的截止时间时放弃重试。
+func (g *Group) SetRetryPolicy(p RetryPolicy) {
+	g.retry.Store(&p)
+}
+
+// retryable 判断远程节点返回的错误是否值得重试
+func retryable(err error) bool {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrCacheMiss) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var t interface{ Temporary() bool }
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	return true
+}
+
+// getFromPeerWithRetry 按 SetRetryPolicy 设置的策略从 peer 获取 key，返回最后一次尝试的结果
+func (g *Group) getFromPeerWithRetry(ctx context.Context, peer pickpeer.PeerGetter, key string) (cache.ByteView, error) {
+	var policy RetryPolicy
+	if p := g.retry.Load(); p != nil {
+		policy = *p
+	}
+	for attempt := 1; ; attempt++ {
+		value, err := g.getFromPeer(ctx, peer, key)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return value, err
+		}
+		wait := policy.delay(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return value, err
+		}
+		log.Println("[GeeCache] Failed to get from peer:", err, "- retrying in", wait)
+		if wait <= 0 {
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return value, err
+		}
+	}
+}
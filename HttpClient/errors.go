@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"fmt"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrorContentType 是 Serve 错误响应的媒体类型：响应体为 protobuf 编码的 pb.Error
+const ErrorContentType = "application/x-geecache-error"
+
+// Error 是远程节点返回的错误。Code 区分 group 不存在、key 不存在与内部错误等类别，
+// 对方不是 Serve（如代理返回的错误页）时 Code 为 pb.ErrorCode_INTERNAL，Message 为响应状态。
+type Error struct {
+	Status  int // HTTP 状态码
+	Code    pb.ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server returned: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Temporary 报告错误是否可能在重试后消失：5xx 是临时错误，4xx 不是
+func (e *Error) Temporary() bool {
+	return e.Status >= 500
+}
+
+// Is 使 errors.Is 能把 KEY_NOT_FOUND 与 CACHE_MISS 识别为 pickpeer.ErrNotFound 与 pickpeer.ErrCacheMiss
+// （即 group.ErrNotFound 与 group.ErrCacheMiss），
+// Group 据此判断远程节点是否已确认 key 不存在
+func (e *Error) Is(target error) bool {
+	switch target {
+	case pickpeer.ErrNotFound:
+		return e.Code == pb.ErrorCode_KEY_NOT_FOUND
+	case pickpeer.ErrCacheMiss:
+		return e.Code == pb.ErrorCode_CACHE_MISS
+	}
+	return false
+}
+
+// readError 将非 200 响应解析为 *Error
+func readError(res *http.Response) error {
+	e := &Error{Status: res.StatusCode, Code: pb.ErrorCode_INTERNAL, Message: res.Status}
+	if res.Header.Get("Content-Type") != ErrorContentType {
+		return e
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return e
+	}
+	out := &pb.Error{}
+	if proto.Unmarshal(body, out) == nil {
+		e.Code, e.Message = out.GetCode(), out.GetMessage()
+	}
+	return e
+}
@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// StreamContentType 是流式批量读取的媒体类型：响应体由若干长度前缀的 protobuf 帧组成
+const StreamContentType = "application/x-geecache-stream"
+
+// maxFrameSize 限制单帧大小，防止损坏的长度前缀导致超大内存分配
+const maxFrameSize = 64 << 20
+
+// WriteFrame 以 4 字节大端长度前缀加 protobuf 编码的形式写出 m
+func WriteFrame(w io.Writer, m proto.Message) error {
+	body, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var prefix [4]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(len(body)))
+	if _, err = w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// ReadFrame 读取一帧并解码到 m，流正常结束时返回 io.EOF
+func ReadFrame(r io.Reader, m proto.Message) error {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > maxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds limit %d", size, maxFrameSize)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return proto.Unmarshal(body, m)
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// healthAlpha 是 EWMA 的平滑系数，越大越看重最近的调用
+const healthAlpha = 0.2
+
+// healthLatencyScale 是健康分的参考延迟：平均延迟等于它时健康分减半
+const healthLatencyScale = 100 * time.Millisecond
+
+// health 记录节点最近调用延迟与错误率的指数加权移动平均
+type health struct {
+	mu      sync.Mutex
+	latency float64 // 纳秒
+	errRate float64
+}
+
+func (h *health) observe(d time.Duration, failed bool) {
+	var e float64
+	if failed {
+		e = 1
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latency += healthAlpha * (float64(d) - h.latency)
+	h.errRate += healthAlpha * (e - h.errRate)
+}
+
+// score 返回平均延迟、错误率以及 (0, 1] 区间的健康分，健康分越高节点越健康
+func (h *health) score() (time.Duration, float64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	score := (1 - h.errRate) / (1 + h.latency/float64(healthLatencyScale))
+	return time.Duration(h.latency), h.errRate, score
+}
@@ -1,45 +1,378 @@
 package httpclient
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	pb "geecache/geecachepb"
 
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 )
 
+// ChecksumHeader 是 Serve 返回的 value 的 SHA-256 校验和（十六进制）响应头
+const ChecksumHeader = "X-Content-SHA256"
+
+// WantChecksumHeader 是请求 ChecksumHeader 的请求头。计算校验和需要遍历整个 value，
+// Serve 只在请求携带该头时才返回 ChecksumHeader，VerifyChecksum 为 true 时 Get 会自动携带
+const WantChecksumHeader = "X-Want-Checksum"
+
+// ForwardedHeader 标记请求来自其他节点：Serve 收到后在本节点加载，不再转发，见 group.Forwarded
+const ForwardedHeader = "X-GeeCache-Forwarded"
+
+// RawContentType 是裸传输模式的媒体类型：响应体即 value 本身，不经过 protobuf 编码
+const RawContentType = "application/x-geecache-raw"
+
+// Checksum 计算 value 的 SHA-256 校验和
+func Checksum(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
 type HttpClient struct {
 	BaseURL string
+	// VerifyChecksum 为 true 时，Get 会用 ChecksumHeader 校验收到的 value，缺失或不一致时返回错误
+	VerifyChecksum bool
+	// Raw 为 true 时请求裸传输模式，跳过 protobuf 编解码；服务端不支持时自动回退到 protobuf
+	Raw bool
+	// Timeout 限制每次请求的总耗时（含读取响应体），0 表示不限制
+	Timeout time.Duration
+	// MaxIdleConnsPerHost 是与每个节点保持的空闲 keep-alive 连接数上限，0 使用 http.DefaultTransport 的默认值（2）。
+	// 并发请求同一节点较多时应调大，否则超出部分的连接用完即关闭，下次请求需重新握手
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout 是空闲连接保持的最长时间，0 使用 http.DefaultTransport 的默认值
+	IdleConnTimeout time.Duration
+	// TLSConfig 非 nil 时用于 https:// 节点的 TLS 握手，如指定信任的 CA、出示客户端证书或固定对端证书，
+	// 见 LoadMutualTLS 与 PinCertificates。连接池使用它的副本，首次请求后再修改不会生效
+	TLSConfig *tls.Config
+	// Token 非空时，每个请求都携带 Authorization: Bearer <Token>，见 HttpAddr.Token
+	Token string
+
+	successes atomic.Int64
+	failures  atomic.Int64
+	nbytes    atomic.Int64
+	health    health
+
+	once sync.Once
+	hc   *http.Client
+}
+
+// PeerStat 是对单个远程节点 Get 调用的统计
+type PeerStat struct {
+	Successes int64
+	Failures  int64
+	Bytes     int64 // 成功读取的响应体总字节数
+
+	Latency   time.Duration // 最近调用延迟的 EWMA
+	ErrorRate float64       // 最近调用错误率的 EWMA
+	Health    float64       // 综合延迟与错误率的健康分，(0, 1]，越高越健康
+}
+
+// String 返回节点的 BaseURL，用于日志与错误信息
+func (h *HttpClient) String() string {
+	return h.BaseURL
+}
+
+// Stat 返回该节点当前的调用统计
+func (h *HttpClient) Stat() PeerStat {
+	latency, errRate, score := h.health.score()
+	return PeerStat{
+		Successes: h.successes.Load(),
+		Failures:  h.failures.Load(),
+		Bytes:     h.nbytes.Load(),
+		Latency:   latency,
+		ErrorRate: errRate,
+		Health:    score,
+	}
+}
+
+// record 将一次 Get 计入统计。只有传输错误与 5xx 算作失败：4xx（包括 KEY_NOT_FOUND 与 CACHE_MISS）
+// 说明节点正常应答；调用方取消或超时与节点健康无关，不计入统计。
+func (h *HttpClient) record(ctx context.Context, n int, d time.Duration, err error) {
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	var e *Error
+	if errors.As(err, &e) && !e.Temporary() {
+		err = nil
+	}
+	h.health.observe(d, err != nil)
+	if err != nil {
+		h.failures.Add(1)
+		return
+	}
+	h.successes.Add(1)
+	h.nbytes.Add(int64(n))
+}
+
+// client 在首次请求时按配置创建该 HttpClient 专属的 *http.Client
+func (h *HttpClient) client() *http.Client {
+	h.once.Do(func() {
+		h.hc = &http.Client{Timeout: h.Timeout, Transport: h.newTransport()}
+	})
+	return h.hc
+}
+
+// newTransport 返回按配置设置连接池与 TLS 的 http.Transport，都未配置时使用 http.DefaultTransport
+func (h *HttpClient) newTransport() http.RoundTripper {
+	if h.MaxIdleConnsPerHost == 0 && h.IdleConnTimeout == 0 && h.TLSConfig == nil {
+		return http.DefaultTransport
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if h.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = h.MaxIdleConnsPerHost
+		// 总量不设上限，由每个节点的上限约束，避免节点较多时互相挤占空闲连接
+		t.MaxIdleConns = 0
+	}
+	if h.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = h.IdleConnTimeout
+	}
+	if h.TLSConfig != nil {
+		// net/http 会在握手时修改配置，使用副本以免与共用该配置的 Server 产生数据竞争
+		t.TLSClientConfig = h.TLSConfig.Clone()
+	}
+	return t
+}
+
+// Close 关闭该 HttpClient 连接池中的空闲连接，进行中的请求不受影响。
+// 使用 http.DefaultTransport 时什么都不做，以免影响共用它的其他客户端
+func (h *HttpClient) Close() {
+	if c := h.client(); c.Transport != http.DefaultTransport {
+		c.CloseIdleConnections()
+	}
 }
 
-func (h *HttpClient) Get(in *pb.Request, out *pb.Response) error{
-	u := fmt.Sprintf(
+// do 发送请求并附加 ForwardedHeader，Token 非空时附加 Authorization 头
+func (h *HttpClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set(ForwardedHeader, "1")
+	if h.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.Token)
+	}
+	return h.client().Do(req)
+}
+
+func (h *HttpClient) url(in *pb.Request) string {
+	return fmt.Sprintf(
 		"%v%v/%v",
 		h.BaseURL,
-		url.QueryEscape(in.GetGroup()),
-		url.QueryEscape(in.GetKey()),
+		EscapeSegment(in.GetGroup()),
+		EscapeSegment(in.GetKey()),
 	)
-    res, err := http.Get(u)
+}
+
+// EscapeSegment 将 group 或 key 编码为单个路径段，可由 url.PathUnescape 精确还原。
+// 除 url.PathEscape 外还会编码 '.'，避免 "." 与 ".." 段被路由器按路径规范化。
+func EscapeSegment(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), ".", "%2E")
+}
+
+func (h *HttpClient) Get(in *pb.Request, out *pb.Response) error {
+	return h.GetContext(context.Background(), in, out)
+}
+
+// GetContext 与 Get 相同，ctx 结束时取消进行中的 HTTP 请求
+func (h *HttpClient) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) (err error) {
+	var n int
+	start := time.Now()
+	defer func() { h.record(ctx, n, time.Since(start), err) }()
+
+	u := h.url(in)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	if h.Raw {
+		req.Header.Set("Accept", RawContentType)
+	}
+	if h.VerifyChecksum {
+		req.Header.Set(WantChecksumHeader, "1")
+	}
+	res, err := h.do(req)
 	if err != nil {
 		return err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("server returned: %v", res.Status)
+		return readError(res)
 	}
 
 	bytes, err := io.ReadAll(res.Body)
 	if err != nil {
 		return fmt.Errorf("reading response body: %v", err)
 	}
+	// 连接中途断开时可能读到不完整的响应体，必须拒绝，以免截断的数据被缓存
+	if res.ContentLength >= 0 && int64(len(bytes)) != res.ContentLength {
+		return fmt.Errorf("reading response body: got %d bytes, want %d", len(bytes), res.ContentLength)
+	}
+	n = len(bytes)
 
-	if err = proto.Unmarshal(bytes, out); err != nil {
+	if res.Header.Get("Content-Type") == RawContentType {
+		out.Value = bytes
+	} else if err = proto.Unmarshal(bytes, out); err != nil {
 		return fmt.Errorf("decoding response body: %v", err)
 	}
 
+	if h.VerifyChecksum {
+		want := res.Header.Get(ChecksumHeader)
+		if want == "" {
+			return fmt.Errorf("verifying response body: missing %s header", ChecksumHeader)
+		}
+		if got := Checksum(out.GetValue()); got != want {
+			return fmt.Errorf("verifying response body: checksum %s, want %s", got, want)
+		}
+	}
+
+	return nil
+}
+
+// HealthPath 是节点健康检查端点相对于基础路径的路径
+const HealthPath = "health"
+
+// Ping 请求远程节点的健康检查端点，节点返回 200 以外的状态时返回错误。
+// 健康检查不计入 Stat 统计。
+func (h *HttpClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.BaseURL+HealthPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return readError(res)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Set 通过 PUT 请求将 value 写入远程节点
+func (h *HttpClient) Set(in *pb.Request, value []byte) error {
+	body, err := proto.Marshal(&pb.Response{Value: value})
+	if err != nil {
+		return fmt.Errorf("encoding request body: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, h.url(in), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return readError(res)
+	}
+	return nil
+}
+
+// Delete 通过 DELETE 请求删除远程节点缓存中的 key
+func (h *HttpClient) Delete(in *pb.Request) error {
+	req, err := http.NewRequest(http.MethodDelete, h.url(in), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return readError(res)
+	}
+	return nil
+}
+
+// SetBatch 通过一次 POST 请求将 entries 批量写入远程节点的 group，返回写入条数
+func (h *HttpClient) SetBatch(group string, entries map[string][]byte) (int64, error) {
+	in := &pb.BatchSetRequest{}
+	for key, value := range entries {
+		in.Entries = append(in.Entries, &pb.Entry{Key: key, Value: value})
+	}
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return 0, fmt.Errorf("encoding request body: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.BaseURL+EscapeSegment(group), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := h.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return 0, readError(res)
+	}
+
+	body, err = io.ReadAll(res.Body)
+	if err != nil {
+		return 0, fmt.Errorf("reading response body: %v", err)
+	}
+	out := &pb.BatchSetResponse{}
+	if err = proto.Unmarshal(body, out); err != nil {
+		return 0, fmt.Errorf("decoding response body: %v", err)
+	}
+	return out.GetStored(), nil
+}
+
+// GetBatch 通过流式批量接口从远程节点的 group 读取 keys，服务端每解析完一个 key 就回调一次 fn，
+// 回调顺序为完成顺序而非 keys 的顺序。单个 key 的加载错误通过 fn 的 err 传递，
+// 返回的 error 仅表示请求或流本身失败。
+func (h *HttpClient) GetBatch(group string, keys []string, fn func(key string, value []byte, err error)) error {
+	q := url.Values{"key": keys}
+	req, err := http.NewRequest(http.MethodGet, h.BaseURL+EscapeSegment(group)+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := h.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return readError(res)
+	}
+
+	for {
+		out := &pb.BatchGetResult{}
+		if err := ReadFrame(res.Body, out); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("reading response frame: %v", err)
+		}
+		if out.GetError() != "" {
+			fn(out.GetKey(), nil, errors.New(out.GetError()))
+			continue
+		}
+		fn(out.GetKey(), out.GetValue(), nil)
+	}
+}
@@ -0,0 +1,344 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ---------- 辅助函数 ----------
+
+func newProtoServer(t *testing.T, value string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(protoHandler(value))
+}
+
+// protoHandler 总是返回 protobuf 编码的 value
+func protoHandler(value string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := proto.Marshal(&pb.Response{Value: []byte(value)})
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	})
+}
+
+// ---------- 基础功能测试 ----------
+
+func TestHttpClient_Get(t *testing.T) {
+	server := newProtoServer(t, "630")
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/"}
+	out := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "scores", Key: "Tom"}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out.GetValue()) != "630" {
+		t.Fatalf("expected '630', got '%s'", out.GetValue())
+	}
+}
+
+func TestHttpClient_GetContext_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := client.GetContext(ctx, &pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("GetContext should return at the deadline, took %v", elapsed)
+	}
+	// 调用方的超时与节点健康无关，不计入统计
+	if stat := client.Stat(); stat.Failures != 0 || stat.Successes != 0 {
+		t.Fatalf("expected the canceled call not to be recorded, got %+v", stat)
+	}
+}
+
+func TestHttpClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := client.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{}); err == nil {
+		t.Fatal("expected timeout error from hung peer")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Get should give up after Timeout, took %v", elapsed)
+	}
+}
+
+// ---------- 连接池测试 ----------
+
+// newConnCountingServer 返回统计新建连接数的 newProtoServer
+func newConnCountingServer(t testing.TB, conns *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Millisecond)
+		body, _ := proto.Marshal(&pb.Response{Value: []byte("630")})
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	return server
+}
+
+// getInBursts 模拟突发流量：每轮同时发起 workers 个 Get，全部完成后再开始下一轮。
+// 每轮结束时空闲连接数超过 MaxIdleConnsPerHost 的部分会被关闭，下一轮需重新建立
+func getInBursts(client *HttpClient, workers, rounds int) {
+	for i := 0; i < rounds; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < workers; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				client.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{})
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestHttpClient_ConnectionPool(t *testing.T) {
+	var conns atomic.Int64
+	server := newConnCountingServer(t, &conns)
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", MaxIdleConnsPerHost: 8, IdleConnTimeout: time.Minute}
+	getInBursts(client, 8, 10)
+	if n := conns.Load(); n > 16 {
+		t.Fatalf("idle connections should be reused, got %d new connections", n)
+	}
+	if client.client().Transport == http.DefaultTransport {
+		t.Fatal("a client with pool settings should not use http.DefaultTransport")
+	}
+	if (&HttpClient{}).client().Transport != http.DefaultTransport {
+		t.Fatal("a client without pool settings should use http.DefaultTransport")
+	}
+}
+
+func TestHttpClient_Close(t *testing.T) {
+	var conns atomic.Int64
+	server := newConnCountingServer(t, &conns)
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", MaxIdleConnsPerHost: 8}
+	getInBursts(client, 1, 2)
+	if n := conns.Load(); n != 1 {
+		t.Fatalf("expected the idle connection to be reused, got %d new connections", n)
+	}
+	// Close 关闭空闲连接，之后的请求需要重新建立连接
+	client.Close()
+	getInBursts(client, 1, 1)
+	if n := conns.Load(); n != 2 {
+		t.Fatalf("expected a new connection after Close, got %d new connections", n)
+	}
+}
+
+func BenchmarkHttpClient_ConnectionPool(b *testing.B) {
+	for _, maxIdle := range []int{0, 64} {
+		b.Run(fmt.Sprintf("MaxIdleConnsPerHost=%d", maxIdle), func(b *testing.B) {
+			var conns atomic.Int64
+			server := newConnCountingServer(b, &conns)
+			defer server.Close()
+			client := &HttpClient{BaseURL: server.URL + "/_geecache/", MaxIdleConnsPerHost: maxIdle, IdleConnTimeout: time.Minute}
+			b.ResetTimer()
+			getInBursts(client, 32, b.N/32+1)
+			b.ReportMetric(float64(conns.Load()), "conns")
+		})
+	}
+}
+
+// ---------- TLS 测试 ----------
+
+func TestHttpClient_TLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(protoHandler("630"))
+	server.StartTLS()
+	defer server.Close()
+	req := &pb.Request{Group: "scores", Key: "Tom"}
+
+	if err := (&HttpClient{BaseURL: server.URL + "/_geecache/"}).Get(req, &pb.Response{}); err == nil {
+		t.Fatal("untrusted certificate should be rejected")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", TLSConfig: &tls.Config{RootCAs: roots}}
+	out := &pb.Response{}
+	if err := client.Get(req, out); err != nil || string(out.GetValue()) != "630" {
+		t.Fatalf("expected '630' over TLS, got %q, %v", out.GetValue(), err)
+	}
+
+	// 固定证书：指纹一致时通过，否则即使证书链可信也拒绝
+	fp := CertificateFingerprint(server.Certificate())
+	client = &HttpClient{BaseURL: server.URL + "/_geecache/", TLSConfig: &tls.Config{RootCAs: roots, VerifyPeerCertificate: PinCertificates(strings.ToUpper(fp))}}
+	if err := client.Get(req, &pb.Response{}); err != nil {
+		t.Fatalf("pinned certificate should be accepted, got %v", err)
+	}
+	client = &HttpClient{BaseURL: server.URL + "/_geecache/", TLSConfig: &tls.Config{RootCAs: roots, VerifyPeerCertificate: PinCertificates(strings.Repeat("0", 64))}}
+	if err := client.Get(req, &pb.Response{}); err == nil || !strings.Contains(err.Error(), "not pinned") {
+		t.Fatalf("unpinned certificate should be rejected, got %v", err)
+	}
+}
+
+// ---------- 校验和测试 ----------
+
+func TestHttpClient_Get_VerifyChecksum(t *testing.T) {
+	body, _ := proto.Marshal(&pb.Response{Value: []byte("630")})
+
+	checksum := Checksum([]byte("630"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ChecksumHeader, checksum)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", VerifyChecksum: true}
+	req := &pb.Request{Group: "scores", Key: "Tom"}
+	if err := client.Get(req, &pb.Response{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 模拟传输中被篡改
+	checksum = Checksum([]byte("631"))
+	if err := client.Get(req, &pb.Response{}); err == nil {
+		t.Fatal("expected checksum mismatch error")
+	}
+
+	// 未开启校验时不受影响
+	client.VerifyChecksum = false
+	if err := client.Get(req, &pb.Response{}); err != nil {
+		t.Fatalf("unexpected error without verification: %v", err)
+	}
+}
+
+func TestHttpClient_Get_MissingChecksum(t *testing.T) {
+	server := newProtoServer(t, "630")
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/", VerifyChecksum: true}
+	if err := client.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{}); err == nil {
+		t.Fatal("expected error when checksum header is missing")
+	}
+}
+
+// ---------- 健康分测试 ----------
+
+func TestHttpClient_Stat_NotFoundIsHealthy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := proto.Marshal(&pb.Error{Code: pb.ErrorCode_KEY_NOT_FOUND, Message: "Key Not Found"})
+		w.Header().Set("Content-Type", ErrorContentType)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/"}
+	for i := 0; i < 20; i++ {
+		if err := client.Get(&pb.Request{Group: "scores", Key: "unknown"}, &pb.Response{}); !errors.Is(err, pickpeer.ErrNotFound) {
+			t.Fatalf("expected not-found error, got %v", err)
+		}
+	}
+	stat := client.Stat()
+	if stat.Failures != 0 || stat.Successes != 20 {
+		t.Fatalf("expected not-found responses to count as successes, got %+v", stat)
+	}
+	if stat.ErrorRate != 0 || stat.Health < 0.5 {
+		t.Fatalf("expected health to stay high, got %+v", stat)
+	}
+}
+
+// ---------- 截断响应测试 ----------
+
+func TestHttpClient_Get_TruncatedBody(t *testing.T) {
+	body, _ := proto.Marshal(&pb.Response{Value: []byte("a fairly long cached value")})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+		// 声明完整长度，但只写出一半后直接断开连接
+		fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", len(body))
+		buf.Write(body[:len(body)/2])
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/"}
+	out := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "scores", Key: "Tom"}, out); err == nil {
+		t.Fatalf("expected error for truncated body, got value '%s'", out.GetValue())
+	}
+	if stat := client.Stat(); stat.Failures != 1 || stat.Successes != 0 {
+		t.Fatalf("expected 1 failure, got %+v", stat)
+	}
+}
+
+func TestHttpClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/proxy/scores/Tom" {
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+			return
+		}
+		body, _ := proto.Marshal(&pb.Error{Code: pb.ErrorCode_GROUP_NOT_FOUND, Message: "Group Not Found"})
+		w.Header().Set("Content-Type", ErrorContentType)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &HttpClient{BaseURL: server.URL + "/_geecache/"}
+	err := client.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{})
+	var e *Error
+	if !errors.As(err, &e) || e.Status != http.StatusNotFound || e.Code != pb.ErrorCode_GROUP_NOT_FOUND || e.Message != "Group Not Found" {
+		t.Fatalf("expected a typed group-not-found error, got %v", err)
+	}
+
+	// 非 Serve 的错误响应归为内部错误
+	client = &HttpClient{BaseURL: server.URL + "/proxy/"}
+	err = client.Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{})
+	if !errors.As(err, &e) || e.Status != http.StatusBadGateway || e.Code != pb.ErrorCode_INTERNAL {
+		t.Fatalf("expected an internal error, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package httpserver
+
+import (
+	"encoding/json"
+	group "geecache/Group"
+	pb "geecache/geecachepb"
+	"net/http"
+)
+
+// adminPath 是管理接口相对于基础路径的前缀，它作为 Group 名被保留，以免 Path/_admin/key 的读取被当作管理请求
+const adminPath = "_admin"
+
+func init() {
+	group.ReserveName(adminPath)
+}
+
+// adminKeys 是 GET Path/_admin/keys?group=X 的响应体
+type adminKeys struct {
+	Group string   `json:"group"`
+	Keys  []string `json:"keys"`
+}
+
+// adminFlush 是 POST Path/_admin/flush?group=X 的响应体，Flushed 是清空前的条目数
+type adminFlush struct {
+	Group   string `json:"group"`
+	Flushed int    `json:"flushed"`
+}
+
+// serveAdmin 处理管理接口，用于排查线上问题：
+//
+//	GET  Path/_admin/keys?group=X   以 JSON 返回 Group 本地缓存中的全部 key
+//	POST Path/_admin/flush?group=X  清空 Group 的本地缓存
+//
+// 管理接口只在设置了 Token 时可用，否则返回 403，避免任何能访问节点的人都能清空缓存
+func (p *HttpAddr) serveAdmin(w http.ResponseWriter, r *http.Request, action string) {
+	if p.Token == "" {
+		httpError(
+			w,
+			pb.ErrorCode_PERMISSION_DENIED,
+			"Admin endpoints require a token",
+		)
+		return
+	}
+	var allow string
+	switch action {
+	case "keys":
+		allow = http.MethodGet
+	case "flush":
+		allow = http.MethodPost
+	default:
+		httpError(
+			w,
+			pb.ErrorCode_BAD_REQUEST,
+			"Unknown admin action",
+		)
+		return
+	}
+	if r.Method != allow {
+		methodNotAllowed(w, allow)
+		return
+	}
+	name := r.URL.Query().Get("group")
+	if name == "" {
+		httpError(
+			w,
+			pb.ErrorCode_BAD_REQUEST,
+			"Missing group",
+		)
+		return
+	}
+	g := group.GetGroup(name)
+	if g == nil {
+		httpError(
+			w,
+			pb.ErrorCode_GROUP_NOT_FOUND,
+			"Group Not Found",
+		)
+		return
+	}
+
+	var resp interface{}
+	if action == "keys" {
+		keys := g.Keys()
+		if keys == nil {
+			keys = []string{}
+		}
+		resp = adminKeys{Group: name, Keys: keys}
+	} else {
+		n := g.Len()
+		g.Clear()
+		p.Log("Flushed %d entries of group %s", n, name)
+		resp = adminFlush{Group: name, Flushed: n}
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		httpError(
+			w,
+			pb.ErrorCode_INTERNAL,
+			err.Error(),
+		)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
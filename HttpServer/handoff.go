@@ -0,0 +1,83 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	cache "geecache/Cache"
+	group "geecache/Group"
+	httpclient "geecache/HttpClient"
+	pb "geecache/geecachepb"
+)
+
+type handoffEntry struct {
+	group  string
+	key    string
+	value  cache.ByteView
+	client *httpclient.HttpClient
+}
+
+// Shutdown 在节点下线前调用。开启 HandoffOnShutdown 时，
+// 会把本节点负责的热点条目推送给哈希环上的下一个节点，避免其冷启动造成的未命中高峰。
+func (p *HttpAddr) Shutdown(ctx context.Context) error {
+	if !p.HandoffOnShutdown {
+		return nil
+	}
+	return p.handoff(ctx)
+}
+
+func (p *HttpAddr) handoff(ctx context.Context) error {
+	entries := p.handoffEntries()
+
+	failed := 0
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		req := &pb.Request{
+			Group: e.group,
+			Key:   e.key,
+		}
+		if err := e.client.Set(req, e.value.ByteSlice()); err != nil {
+			p.Log("Failed to hand off %s/%s to %s: %v", e.group, e.key, e.client.BaseURL, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("handoff: %d/%d entries failed", failed, len(entries))
+	}
+	return nil
+}
+
+// handoffEntries 收集本节点负责的条目，以及本节点下线后接管它们的节点
+func (p *HttpAddr) handoffEntries() []handoffEntry {
+	var entries []handoffEntry
+	for _, name := range group.ListGroups() {
+		g := group.GetGroup(name)
+		if g == nil {
+			continue
+		}
+		n := 0
+		g.Range(func(key string, value cache.ByteView) bool {
+			if client := p.successor(key); client != nil {
+				entries = append(entries, handoffEntry{name, key, value, client})
+				n++
+			}
+			return p.HandoffLimit == 0 || n < p.HandoffLimit
+		})
+	}
+	return entries
+}
+
+// successor 返回本节点下线后接管 key 的节点；key 不归本节点负责时返回 nil
+func (p *HttpAddr) successor(key string) *httpclient.HttpClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	candidates := p.peers.GetN(key, 2)
+	if len(candidates) < 2 || candidates[0] != p.self() {
+		return nil
+	}
+	return p.HttpClients[candidates[1]]
+}
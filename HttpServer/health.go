@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"context"
+	httpclient "geecache/HttpClient"
+	"sync"
+	"time"
+)
+
+// healthCheckFailures 是节点被移出哈希环前健康检查需要连续失败的次数
+const healthCheckFailures = 3
+
+// EnableHealthCheck 每隔 interval 并发请求一次所有远程节点的健康检查端点。
+// 连续失败 healthCheckFailures 次的节点会被移出哈希环，它负责的 key 交给环上的下一个节点，
+// 避免每次请求都先访问宕机节点；节点恢复后按原权重加回。
+// 再次调用会替换之前的健康检查，interval 小于等于 0 时停止健康检查；Server.Shutdown 后自动停止。
+func (p *HttpAddr) EnableHealthCheck(interval time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopHealth != nil {
+		close(p.stopHealth)
+		p.stopHealth = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	p.stopHealth = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			if p.closing.Load() {
+				return
+			}
+			p.checkHealth(interval)
+		}
+	}()
+}
+
+// checkHealth 对所有远程节点（包括已被移出哈希环的）执行一次健康检查
+func (p *HttpAddr) checkHealth(timeout time.Duration) {
+	p.mu.Lock()
+	clients := make(map[string]*httpclient.HttpClient, len(p.HttpClients))
+	for peer, client := range p.HttpClients {
+		if peer != p.self() {
+			clients[peer] = client
+		}
+	}
+	p.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	for peer, client := range clients {
+		wg.Add(1)
+		go func(peer string, client *httpclient.HttpClient) {
+			defer wg.Done()
+			p.markHealth(peer, client.Ping(ctx) == nil)
+		}(peer, client)
+	}
+	wg.Wait()
+}
+
+// markHealth 记录一次健康检查结果，必要时把节点移出或加回哈希环
+func (p *HttpAddr) markHealth(peer string, healthy bool) {
+	if p.closing.Load() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// 检查期间节点可能已被 Set 或 WatchChan 移除
+	if _, ok := p.HttpClients[peer]; !ok || p.peers == nil {
+		return
+	}
+
+	if healthy {
+		delete(p.probeFailures, peer)
+		weight, ok := p.down[peer]
+		if !ok {
+			return
+		}
+		delete(p.down, peer)
+		p.remember(p.peers.Clone())
+		p.peers.AddWeightedKeys(map[string]int{peer: weight})
+		p.Log("Peer %s recovered, added back to the ring", peer)
+		return
+	}
+
+	if p.probeFailures == nil {
+		p.probeFailures = make(map[string]int)
+	}
+	p.probeFailures[peer]++
+	if _, ok := p.down[peer]; ok || p.probeFailures[peer] < healthCheckFailures {
+		return
+	}
+	if p.down == nil {
+		p.down = make(map[string]int)
+	}
+	p.down[peer] = p.peers.Weight(peer)
+	p.remember(p.peers.Clone())
+	p.peers.Remove(peer)
+	p.Log("Peer %s failed %d health checks, removed from the ring", peer, p.probeFailures[peer])
+}
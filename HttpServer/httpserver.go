@@ -1,49 +1,528 @@
 package httpserver
 
 import (
+	"crypto/tls"
 	consistenthash "geecache/ConsistentHash"
 	httpclient "geecache/HttpClient"
+	pickpeer "geecache/PickPeer"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-var defaultBasePath = "/_geecache/"
+const defaultBasePath = "/_geecache/"
 
-const num = 50
+
+// defaultHealthCandidates 是 PreferHealthy 开启时默认参与比较的候选节点数
+const defaultHealthCandidates = 2
+
+// defaultBatchConcurrency 是 BatchConcurrency 为 0 时单个批量读取请求同时加载的 key 数
+const defaultBatchConcurrency = 16
+
+// defaultMaxBodyBytes 是 MaxBodyBytes 为 0 时写入请求体的大小上限
+const defaultMaxBodyBytes = 64 << 20
+
+// defaultPeerTimeout 是节点间请求的默认超时时间
+const defaultPeerTimeout = 3 * time.Second
+
+// 节点间连接池的默认配置。http.DefaultTransport 每个节点只保留 2 个空闲连接，
+// 并发请求同一节点时大量连接用完即关闭，因此默认调大
+const (
+	defaultMaxIdleConnsPerHost = 64
+	defaultIdleConnTimeout     = 90 * time.Second
+)
 
 type HttpAddr struct {
 	Host string
+	// Advertise 是其他节点访问本节点所用的地址，即出现在节点列表与哈希环中的地址。
+	// 经过 NAT 或代理时它可能与 Host 不同；为空时使用 Host。
+	Advertise string
 	Path string
 	mu   sync.Mutex
 	peers *consistenthash.Map
 	HttpClients map[string]*httpclient.HttpClient
+
+	// VerifyChecksum 为 true 时，节点间的 Get 会校验响应中的 value 校验和
+	VerifyChecksum bool
+	// RawTransport 为 true 时，节点间的 Get 使用裸传输模式，跳过 protobuf 编解码
+	RawTransport bool
+	// PeerTimeout 是节点间每次请求的超时时间，0 使用默认的 3 秒，小于 0 表示不限制
+	PeerTimeout time.Duration
+	// MaxIdleConnsPerHost 是与每个节点保持的空闲 keep-alive 连接数上限，0 使用 defaultMaxIdleConnsPerHost。
+	// 节点间并发请求较多时调大可减少连接反复建立的握手开销
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout 是节点间空闲连接保持的最长时间，0 使用 defaultIdleConnTimeout
+	IdleConnTimeout time.Duration
+	// TLSConfig 非 nil 时用于向 https:// 节点发起请求，见 httpclient.HttpClient.TLSConfig。
+	// 本节点自身以 HTTPS 提供服务见 Server.StartTLS
+	TLSConfig *tls.Config
+	// Token 非空时启用节点间认证：发往其他节点的请求携带 Authorization: Bearer <Token>，
+	// Serve 拒绝未携带相同 Token 的请求（健康检查除外），返回 401。集群内所有节点必须配置相同的值
+	Token string
+
+	// GzipThreshold 大于 0 时，客户端发送 Accept-Encoding: gzip 且响应体不小于该字节数时，
+	// 裸传输模式的响应会被 gzip 压缩
+	GzipThreshold int
+	// GzipProto 为 true 时，protobuf 响应也按 GzipThreshold 压缩
+	GzipProto bool
+
+	// PreferHealthy 为 true 时，PickPeer 会在 key 的前 HealthCandidates 个候选节点中选择健康分最高的远程节点；
+	// 被选中的非归属节点直接在本地加载而不再转发（见 httpclient.ForwardedHeader）。
+	// 为 false 时路由完全由一致性哈希决定
+	PreferHealthy bool
+	// HealthCandidates 为 0 时使用 defaultHealthCandidates
+	HealthCandidates int
+
+	// HandoffOnShutdown 为 true 时，Shutdown 会把本节点负责的热点条目推送给下线后接管它们的节点
+	HandoffOnShutdown bool
+	// HandoffLimit 限制每个 Group 推送的条目数（按 cache.Cache.Range 的顺序，越晚淘汰的越优先），0 表示不限制
+	HandoffLimit int
+
+	// ReshuffleWindow 大于 0 时，节点列表变更后的该时长内，本节点新接管的 key 未命中时
+	// 会先向变更前的归属节点（仍在节点列表中时）取值，再回源加载
+	ReshuffleWindow time.Duration
+	// prevPeers 是最近一次变更前的哈希环，changedAt 是变更时间
+	prevPeers *consistenthash.Map
+	changedAt time.Time
+
+	// HashSeed 非空时哈希环使用以它为密钥的哈希（见 consistenthash.Keyed），防止攻击者构造冲突的 key；
+	// 集群内所有节点必须配置相同的值，且应在第一次 Set 之前设置
+	HashSeed []byte
+	// HashFunc 非 nil 时哈希环使用该哈希函数（如 FNV、xxHash），便于与其他语言的服务保持一致的路由，
+	// 同时设置时优先于 HashSeed；两者都未设置时使用 crc32。集群内所有节点必须使用相同的函数，
+	// 且应在第一次 Set 之前设置
+	HashFunc consistenthash.Hash
+
+	// Replicas 是每个节点（权重为 1 时）在哈希环上的虚拟节点数，为 0 时使用 consistenthash.DefaultReplicas。
+	// 越大 key 在节点间分布越均匀，但哈希环占用的内存与 Set 的耗时随 节点数×Replicas 增长：
+	// 节点很少时可以调大以改善均衡，上百个节点时可以调小。集群内所有节点必须配置相同的值，
+	// 且应在第一次 Set 之前设置
+	Replicas int
+
+	// BatchConcurrency 限制单个批量读取请求同时加载的 key 数，避免一个包含大量 key 的请求
+	// 一次启动成千上万个加载；为 0 时使用 defaultBatchConcurrency
+	BatchConcurrency int
+
+	// MaxBodyBytes 限制 PUT 与批量写入请求体的字节数，超出时返回 413；
+	// 为 0 时使用 defaultMaxBodyBytes（64 MiB），小于 0 表示不限制
+	MaxBodyBytes int64
+
+	// closing 在 Server.Shutdown 开始后为 true，此后不再接受节点列表变更
+	closing atomic.Bool
+
+	// stopHealth 用于停止 EnableHealthCheck 启动的健康检查，nil 表示未开启
+	stopHealth chan struct{}
+	// probeFailures 是各节点健康检查连续失败的次数
+	probeFailures map[string]int
+	// down 是因健康检查失败被移出哈希环的节点及其权重，恢复后按原权重加回
+	down map[string]int
+	// weights 是 SetWeighted 配置的权重，WatchChan 更新加入节点时按它恢复权重；Set 会清空它
+	weights map[string]int
+
+	// paths 是通过 AddPath 注册的其他基础路径，每个都有独立的哈希环与 HttpClient
+	paths map[string]*HttpAddr
+}
+
+// ClientConfig 是 HttpAddr 创建 HttpClient 时使用的配置，含义同 HttpAddr 的同名字段
+type ClientConfig struct {
+	VerifyChecksum bool
+	RawTransport   bool
+	PeerTimeout    time.Duration
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	TLSConfig           *tls.Config
 }
 
 func NewHttpAddr(host string) *HttpAddr {
+	return NewHttpAddrWithPath(host, defaultBasePath)
+}
+
+// NewHttpAddrWithPath 与 NewHttpAddr 相同，但使用 path 作为基础路径（如 "/cacheB/"），
+// Serve 只处理该路径下的请求，节点间请求也发往各节点的该路径。
+// 同一进程中的多个集群可以用不同的路径挂在同一个路由器上；集群内所有节点必须使用相同的路径。
+// path 缺少首尾的 '/' 时会自动补全。
+func NewHttpAddrWithPath(host, path string) *HttpAddr {
 	return &HttpAddr{
 		Host: host,
-		Path: defaultBasePath,
+		Path: cleanPath(path),
+	}
+}
+
+// cleanPath 确保基础路径以 '/' 开头和结尾
+func cleanPath(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
 	}
+	return path
 }
 
 
 func (p *HttpAddr) Set(peers ...string) {
+	if p.frozen() {
+		return
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	p.peers = consistenthash.New(num, nil)
+	p.remember(p.peers)
+	p.peers = p.newRing()
 	p.peers.AddKeys(peers...)
+	p.weights = nil
+	p.setClients(peers)
+}
+
+// newRing 返回按 Replicas、HashFunc 与 HashSeed 配置的空哈希环
+func (p *HttpAddr) newRing() *consistenthash.Map {
+	replicas := p.Replicas
+	if replicas == 0 {
+		replicas = consistenthash.DefaultReplicas
+	}
+	hash := p.HashFunc
+	if hash == nil && len(p.HashSeed) > 0 {
+		hash = consistenthash.Keyed(p.HashSeed)
+	}
+	return consistenthash.New(replicas, hash)
+}
+
+// frozen 判断节点是否正在下线，下线期间忽略节点列表变更
+func (p *HttpAddr) frozen() bool {
+	if p.closing.Load() {
+		p.Log("Shutting down, ignoring peer update")
+		return true
+	}
+	return false
+}
+
+// self 返回本节点在哈希环中的地址
+func (p *HttpAddr) self() string {
+	if p.Advertise != "" {
+		return p.Advertise
+	}
+	return p.Host
+}
+
+// SetWeighted 与 Set 相同，但按权重分配虚拟节点数，权重越高的节点分到的 key 越多
+func (p *HttpAddr) SetWeighted(weights map[string]int) {
+	if p.frozen() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.remember(p.peers)
+	p.peers = p.newRing()
+	p.peers.AddWeightedKeys(weights)
+	p.weights = make(map[string]int, len(weights))
+	for peer, weight := range weights {
+		p.weights[peer] = weight
+	}
+	peers := make([]string, 0, len(weights))
+	for peer := range weights {
+		peers = append(peers, peer)
+	}
+	p.setClients(peers)
+}
+
+func (p *HttpAddr) setClients(peers []string) {
+	p.probeFailures = nil
+	p.down = nil
+	for _, client := range p.HttpClients {
+		client.Close()
+	}
 	p.HttpClients = make(map[string]*httpclient.HttpClient,len(peers))
 	for _, peer := range peers {
-		p.HttpClients[peer] = &httpclient.HttpClient{BaseURL: peer + p.Path}
+		p.HttpClients[peer] = p.newClient(peer)
 	}
 }
 
 
-func (p *HttpAddr) PickPeer(key string) (*httpclient.HttpClient, bool) {
+// HttpAddr 实现 Group 所需的节点选择接口，可直接传给 Group.RegisterPeers
+var (
+	_ pickpeer.PeerPicker          = (*HttpAddr)(nil)
+	_ pickpeer.ReplicaPicker       = (*HttpAddr)(nil)
+	_ pickpeer.PreviousOwnerPicker = (*HttpAddr)(nil)
+)
+
+// PickPeer 返回 key 所属的远程节点，key 归本节点负责时返回 nil, false。
+// 返回的 PeerGetter 是 *httpclient.HttpClient，同时实现了 PeerSetter 与 PeerDeleter。
+func (p *HttpAddr) PickPeer(key string) (pickpeer.PeerGetter, bool) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if peer := p.peers.Get(key); peer != "" && peer != p.Host {
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(key); peer != "" && peer != p.self() {
+		if p.PreferHealthy {
+			peer = p.healthiest(key)
+		}
 		p.Log("Pick peer %s", peer)
-		return p.HttpClients[peer], true
+		if client, ok := p.HttpClients[peer]; ok {
+			return client, true
+		}
 	}
 	return nil, false
-}
\ No newline at end of file
+}
+
+// remember 记录变更前的哈希环，供 PickPreviousOwner 使用
+func (p *HttpAddr) remember(prev *consistenthash.Map) {
+	if prev == nil {
+		return
+	}
+	p.prevPeers = prev
+	p.changedAt = time.Now()
+}
+
+// PickPreviousOwner 实现 pickpeer.PreviousOwnerPicker：ReshuffleWindow 内，若 key 现在归本节点而变更前
+// 归另一个仍在节点列表中的节点，返回该节点
+func (p *HttpAddr) PickPreviousOwner(key string) (pickpeer.PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ReshuffleWindow <= 0 || p.prevPeers == nil || time.Since(p.changedAt) > p.ReshuffleWindow {
+		return nil, false
+	}
+	if p.peers.Get(key) != p.self() {
+		return nil, false
+	}
+	prev := p.prevPeers.Get(key)
+	if prev == "" || prev == p.self() {
+		return nil, false
+	}
+	client, ok := p.HttpClients[prev]
+	if !ok {
+		return nil, false
+	}
+	return client, true
+}
+
+// healthiest 返回 key 的候选远程节点中健康分最高的一个，健康分相同时按哈希环顺序优先
+func (p *HttpAddr) healthiest(key string) string {
+	n := p.HealthCandidates
+	if n <= 0 {
+		n = defaultHealthCandidates
+	}
+	best, bestScore := "", -1.0
+	for _, peer := range p.peers.GetN(key, n) {
+		if peer == p.self() {
+			continue
+		}
+		if score := p.HttpClients[peer].Stat().Health; score > bestScore {
+			best, bestScore = peer, score
+		}
+	}
+	return best
+}
+
+// AddPath 在同一个 HttpAddr 上注册另一个基础路径（如 "/cacheB/"），返回该路径对应的 HttpAddr。
+// 返回值拥有独立的哈希环与 HttpClient，需要单独调用 Set 并注册到对应的 Group；
+// 其余配置字段不会从 p 继承。p 的 Handler/Serve 会按路径前缀把请求转发给它。
+func (p *HttpAddr) AddPath(path string) *HttpAddr {
+	path = cleanPath(path)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paths == nil {
+		p.paths = make(map[string]*HttpAddr)
+	}
+	if sub, ok := p.paths[path]; ok {
+		return sub
+	}
+	sub := NewHttpAddrWithPath(p.Host, path)
+	sub.Advertise = p.Advertise
+	sub.HashSeed = p.HashSeed
+	sub.HashFunc = p.HashFunc
+	sub.Replicas = p.Replicas
+	sub.BatchConcurrency = p.BatchConcurrency
+	sub.MaxBodyBytes = p.MaxBodyBytes
+	sub.Token = p.Token
+	p.paths[path] = sub
+	return sub
+}
+
+// route 返回负责该请求路径的 HttpAddr，多个路径都匹配时取最长前缀
+func (p *HttpAddr) route(path string) *HttpAddr {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	best, bestLen := p, -1
+	if strings.HasPrefix(path, p.Path) {
+		bestLen = len(p.Path)
+	}
+	for prefix, sub := range p.paths {
+		if len(prefix) > bestLen && strings.HasPrefix(path, prefix) {
+			best, bestLen = sub, len(prefix)
+		}
+	}
+	return best
+}
+
+// RingDigest 返回当前哈希环的稳定摘要，见 consistenthash.Map.Digest。
+// 节点列表、权重、Replicas 与 HashSeed 都相同的节点摘要相同，运维可比较各节点的摘要确认路由一致。
+func (p *HttpAddr) RingDigest() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return p.newRing().Digest()
+	}
+	return p.peers.Digest()
+}
+
+// Candidates 按哈希环顺序返回 key 的全部候选节点（不去除自身），仅用于调试路由
+func (p *HttpAddr) Candidates(key string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil
+	}
+	return p.peers.GetN(key, len(p.HttpClients))
+}
+
+// PickPeers 按哈希环顺序返回 key 的至多 n 个远程节点，跳过自身
+func (p *HttpAddr) PickPeers(key string, n int) []pickpeer.PeerGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil || n <= 0 {
+		return nil
+	}
+	peers := make([]pickpeer.PeerGetter, 0, n)
+	for _, peer := range p.peers.GetN(key, len(p.HttpClients)) {
+		if peer == p.self() {
+			continue
+		}
+		peers = append(peers, p.HttpClients[peer])
+		if len(peers) == n {
+			break
+		}
+	}
+	return peers
+}
+
+// PeerStats 返回每个远程节点的 Get 调用统计，key 为节点地址
+func (p *HttpAddr) PeerStats() map[string]httpclient.PeerStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make(map[string]httpclient.PeerStat, len(p.HttpClients))
+	for peer, client := range p.HttpClients {
+		stats[peer] = client.Stat()
+	}
+	return stats
+}
+
+// MovedKeys 计算把节点列表换成 peers 后，keys 中哪些 key 会改变归属节点，
+// 可用于扩容前有针对性地预热缓存
+func (p *HttpAddr) MovedKeys(peers []string, keys []string) []consistenthash.Move {
+	next := p.newRing()
+	next.AddKeys(peers...)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := p.peers
+	if current == nil {
+		current = p.newRing()
+	}
+	return consistenthash.Diff(current, next, keys)
+}
+
+// ReconfigureClients 按 cfg 更新客户端配置并重建所有 HttpClient，哈希环保持不变。
+// 旧 HttpClient 的空闲连接随之关闭，新建的 HttpClient 统计与健康分从零开始。
+func (p *HttpAddr) ReconfigureClients(cfg ClientConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.VerifyChecksum = cfg.VerifyChecksum
+	p.RawTransport = cfg.RawTransport
+	p.PeerTimeout = cfg.PeerTimeout
+	p.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	p.IdleConnTimeout = cfg.IdleConnTimeout
+	p.TLSConfig = cfg.TLSConfig
+	for peer, client := range p.HttpClients {
+		client.Close()
+		p.HttpClients[peer] = p.newClient(peer)
+	}
+}
+
+func (p *HttpAddr) newClient(peer string) *httpclient.HttpClient {
+	timeout := p.PeerTimeout
+	if timeout == 0 {
+		timeout = defaultPeerTimeout
+	} else if timeout < 0 {
+		timeout = 0
+	}
+	maxIdle := p.MaxIdleConnsPerHost
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdleConnsPerHost
+	}
+	idleTimeout := p.IdleConnTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+	return &httpclient.HttpClient{
+		BaseURL:             peer + p.Path,
+		VerifyChecksum:      p.VerifyChecksum,
+		Raw:                 p.RawTransport,
+		Timeout:             timeout,
+		MaxIdleConnsPerHost: maxIdle,
+		IdleConnTimeout:     idleTimeout,
+		TLSConfig:           p.TLSConfig,
+		Token:               p.Token,
+	}
+}
+
+// WatchChan 在后台依次应用 ch 中收到的节点列表，ch 关闭后停止。
+// 每次更新只增删有变化的节点，未变化节点的哈希环位置与 HttpClient 保持不变。
+// 之前由 SetWeighted 配置过权重的节点保留（或重新加入时恢复）其权重，其他新节点的权重为 1。
+func (p *HttpAddr) WatchChan(ch <-chan []string) {
+	go func() {
+		for peers := range ch {
+			p.update(peers)
+		}
+	}()
+}
+
+// update 将节点列表增量更新为 peers
+func (p *HttpAddr) update(peers []string) {
+	if p.frozen() {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		p.peers = p.newRing()
+		p.HttpClients = make(map[string]*httpclient.HttpClient, len(peers))
+	}
+
+	next := make(map[string]bool, len(peers))
+	for _, peer := range peers {
+		next[peer] = true
+	}
+	var removed, added []string
+	for peer := range p.HttpClients {
+		if !next[peer] {
+			removed = append(removed, peer)
+		}
+	}
+	for peer := range next {
+		if _, ok := p.HttpClients[peer]; !ok {
+			added = append(added, peer)
+		}
+	}
+	if len(removed) == 0 && len(added) == 0 {
+		return
+	}
+	p.remember(p.peers.Clone())
+
+	for _, peer := range removed {
+		p.peers.Remove(peer)
+		p.HttpClients[peer].Close()
+		delete(p.HttpClients, peer)
+		delete(p.probeFailures, peer)
+		delete(p.down, peer)
+	}
+	for _, peer := range added {
+		p.HttpClients[peer] = p.newClient(peer)
+	}
+	if len(added) > 0 {
+		weights := make(map[string]int, len(added))
+		for _, peer := range added {
+			weights[peer] = p.weights[peer]
+		}
+		p.peers.AddWeightedKeys(weights)
+	}
+}
@@ -1,19 +1,27 @@
 package httpserver
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	callbackfunc "geecache/CallbackFunc"
+	consistenthash "geecache/ConsistentHash"
 	group "geecache/Group"
+	httpclient "geecache/HttpClient"
+	pb "geecache/geecachepb"
+	"hash/fnv"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
 )
 
 // ---------- 测试数据 ----------
@@ -37,11 +45,21 @@ func createTestGroup(name string) *group.Group {
 		}))
 }
 
-func setupTestRouter(httpAddr *HttpAddr) *gin.Engine {
-	gin.SetMode(gin.TestMode)
-	r := gin.New()
-	r.GET("/_geecache/*path", httpAddr.Serve)
-	return r
+// decodeBody 解析 Serve 返回的 protobuf 响应体，返回其中的 value
+func decodeBody(t *testing.T, r io.Reader) string {
+	t.Helper()
+	body, _ := io.ReadAll(r)
+	res := &pb.Response{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		t.Fatalf("decoding response body: %v", err)
+	}
+	return string(res.GetValue())
+}
+
+func setupTestRouter(httpAddr *HttpAddr) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/_geecache/", httpAddr.Handler())
+	return mux
 }
 
 // ---------- HttpAddr 基础功能测试 ----------
@@ -100,7 +118,7 @@ func TestHttpAddr_PickPeer_Self(t *testing.T) {
 	// 测试多个 key，验证不会选择自身
 	for i := 0; i < 100; i++ {
 		key := fmt.Sprintf("test-key-%d", i)
-		client, ok := httpAddr.PickPeer(key)
+		client, ok := pickClient(httpAddr, key)
 		if ok {
 			// 如果选择了节点，不应该是自身
 			if client.BaseURL == host+defaultBasePath {
@@ -110,6 +128,26 @@ func TestHttpAddr_PickPeer_Self(t *testing.T) {
 	}
 }
 
+// pickClient 调用 PickPeer 并取出底层的 HttpClient
+func pickClient(p *HttpAddr, key string) (*httpclient.HttpClient, bool) {
+	peer, ok := p.PickPeer(key)
+	if !ok {
+		return nil, false
+	}
+	return peer.(*httpclient.HttpClient), true
+}
+
+func TestHttpAddr_PickPeer_Advertise(t *testing.T) {
+	httpAddr := NewHttpAddr("http://0.0.0.0:8001")
+	httpAddr.Advertise = "http://10.0.0.1:8001"
+	httpAddr.Set("http://10.0.0.1:8001")
+
+	// 哈希环中只有本节点的对外地址，所有 key 都应识别为本地
+	if _, ok := httpAddr.PickPeer("Tom"); ok {
+		t.Fatal("advertised address should be recognized as self")
+	}
+}
+
 func TestHttpAddr_PickPeer_Consistency(t *testing.T) {
 	httpAddr := NewHttpAddr("http://localhost:8001")
 	peers := []string{
@@ -121,10 +159,10 @@ func TestHttpAddr_PickPeer_Consistency(t *testing.T) {
 
 	// 同一个 key 应该始终路由到同一个节点
 	key := "consistent-key"
-	firstClient, firstOk := httpAddr.PickPeer(key)
+	firstClient, firstOk := pickClient(httpAddr, key)
 
 	for i := 0; i < 10; i++ {
-		client, ok := httpAddr.PickPeer(key)
+		client, ok := pickClient(httpAddr, key)
 		if ok != firstOk {
 			t.Fatalf("consistency check failed: ok mismatch")
 		}
@@ -134,6 +172,389 @@ func TestHttpAddr_PickPeer_Consistency(t *testing.T) {
 	}
 }
 
+func TestHttpAddr_Candidates(t *testing.T) {
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	if got := httpAddr.Candidates("key"); got != nil {
+		t.Fatalf("expected no candidates before Set, got %v", got)
+	}
+
+	peers := []string{
+		"http://localhost:8001",
+		"http://localhost:8002",
+		"http://localhost:8003",
+	}
+	httpAddr.Set(peers...)
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("candidate-key-%d", i)
+		candidates := httpAddr.Candidates(key)
+		if len(candidates) != len(peers) {
+			t.Fatalf("key %s: expected %d candidates, got %v", key, len(peers), candidates)
+		}
+		// 第一个候选节点即 key 的归属节点
+		if client, ok := pickClient(httpAddr, key); ok {
+			if client.BaseURL != candidates[0]+defaultBasePath {
+				t.Fatalf("key %s: first candidate %s does not match picked peer %s", key, candidates[0], client.BaseURL)
+			}
+		} else if candidates[0] != httpAddr.Host {
+			t.Fatalf("key %s: expected self as first candidate, got %s", key, candidates[0])
+		}
+	}
+}
+
+func TestHttpAddr_PickPeers(t *testing.T) {
+	host := "http://localhost:8001"
+	httpAddr := NewHttpAddr(host)
+	httpAddr.Set(
+		"http://localhost:8001",
+		"http://localhost:8002",
+		"http://localhost:8003",
+	)
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("replica-key-%d", i)
+		peers := httpAddr.PickPeers(key, 3)
+		if len(peers) != 2 {
+			t.Fatalf("key %s: expected 2 remote peers, got %d", key, len(peers))
+		}
+		for _, peer := range peers {
+			if peer.(*httpclient.HttpClient).BaseURL == host+defaultBasePath {
+				t.Fatalf("key %s: should not pick self as replica", key)
+			}
+		}
+	}
+}
+
+func TestHttpAddr_PeerStats(t *testing.T) {
+	_ = createTestGroup("scores_stats")
+
+	server := httptest.NewServer(setupTestRouter(NewHttpAddr("")))
+	defer server.Close()
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.Set(server.URL)
+	client := httpAddr.HttpClients[server.URL]
+
+	out := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "scores_stats", Key: "Tom"}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.Get(&pb.Request{Group: "scores_stats", Key: "Nobody"}, out); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+
+	stat := httpAddr.PeerStats()[server.URL]
+	if stat.Successes != 1 || stat.Failures != 1 {
+		t.Fatalf("expected 1 success and 1 failure, got %+v", stat)
+	}
+	if stat.Bytes == 0 {
+		t.Fatal("expected transferred bytes to be recorded")
+	}
+}
+
+func TestHttpAddr_SetWeighted(t *testing.T) {
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	weights := map[string]int{
+		"http://localhost:8002": 1,
+		"http://localhost:8003": 3,
+	}
+	httpAddr.SetWeighted(weights)
+
+	if len(httpAddr.HttpClients) != len(weights) {
+		t.Fatalf("expected %d HttpClients, got %d", len(weights), len(httpAddr.HttpClients))
+	}
+
+	counts := make(map[string]int)
+	const numKeys = 10000
+	for i := 0; i < numKeys; i++ {
+		client, ok := pickClient(httpAddr, fmt.Sprintf("weighted-key-%d", i))
+		if !ok {
+			t.Fatal("expected a remote peer")
+		}
+		counts[client.BaseURL]++
+	}
+
+	// 权重 3:1，允许一定偏差
+	ratio := float64(counts["http://localhost:8003"+defaultBasePath]) / float64(counts["http://localhost:8002"+defaultBasePath])
+	if ratio < 2 || ratio > 4.5 {
+		t.Fatalf("expected roughly 3x keys on the heavier peer, got ratio %.2f (%v)", ratio, counts)
+	}
+}
+
+func TestHttpAddr_WatchChan(t *testing.T) {
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.Set("http://localhost:8002", "http://localhost:8003")
+	kept := httpAddr.HttpClients["http://localhost:8003"]
+
+	ch := make(chan []string)
+	httpAddr.WatchChan(ch)
+	ch <- []string{"http://localhost:8003", "http://localhost:8004"}
+	ch <- []string{"http://localhost:8003", "http://localhost:8004"}
+	close(ch)
+
+	// 等待后台 goroutine 应用完最后一次更新
+	deadline := time.Now().Add(time.Second)
+	for {
+		httpAddr.mu.Lock()
+		_, added := httpAddr.HttpClients["http://localhost:8004"]
+		httpAddr.mu.Unlock()
+		if added || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	httpAddr.mu.Lock()
+	defer httpAddr.mu.Unlock()
+	if len(httpAddr.HttpClients) != 2 {
+		t.Fatalf("expected 2 clients, got %d", len(httpAddr.HttpClients))
+	}
+	if _, exists := httpAddr.HttpClients["http://localhost:8002"]; exists {
+		t.Fatal("removed peer should not exist")
+	}
+	if httpAddr.HttpClients["http://localhost:8003"] != kept {
+		t.Fatal("unchanged peer should keep its HttpClient")
+	}
+	for i := 0; i < 100; i++ {
+		if peer := httpAddr.peers.Get(fmt.Sprintf("key-%d", i)); peer == "http://localhost:8002" {
+			t.Fatal("removed peer should not own any key")
+		}
+	}
+}
+
+func TestHttpAddr_WatchChanWeighted(t *testing.T) {
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.SetWeighted(map[string]int{
+		"http://localhost:8002": 1,
+		"http://localhost:8003": 3,
+	})
+
+	// 加权节点先被移除再重新加入，权重应当恢复；新节点权重为 1
+	httpAddr.update([]string{"http://localhost:8002", "http://localhost:8004"})
+	httpAddr.update([]string{"http://localhost:8002", "http://localhost:8003", "http://localhost:8004"})
+
+	httpAddr.mu.Lock()
+	defer httpAddr.mu.Unlock()
+	want := map[string]int{
+		"http://localhost:8002": 1,
+		"http://localhost:8003": 3,
+		"http://localhost:8004": 1,
+	}
+	for peer, weight := range want {
+		if got := httpAddr.peers.Weight(peer); got != weight {
+			t.Errorf("%s: expected weight %d, got %d", peer, weight, got)
+		}
+	}
+}
+
+func TestHttpAddr_PickPreviousOwner(t *testing.T) {
+	self, other := "http://localhost:8001", "http://localhost:8002"
+	httpAddr := NewHttpAddr(self)
+	httpAddr.ReshuffleWindow = time.Minute
+	httpAddr.SetWeighted(map[string]int{self: 1, other: 3})
+	prev := httpAddr.peers
+	// 提高自身权重，使部分 key 从 other 转移到本节点
+	httpAddr.SetWeighted(map[string]int{self: 3, other: 3})
+
+	moved := ""
+	for i := 0; i < 1000 && moved == ""; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if prev.Get(key) == other && httpAddr.peers.Get(key) == self {
+			moved = key
+		}
+	}
+	if moved == "" {
+		t.Fatal("expected some key to move to self")
+	}
+
+	peer, ok := httpAddr.PickPreviousOwner(moved)
+	if !ok || peer != httpAddr.HttpClients[other] {
+		t.Fatalf("should pick previous owner %s, got %v %v", other, peer, ok)
+	}
+
+	httpAddr.mu.Lock()
+	httpAddr.changedAt = time.Now().Add(-2 * time.Minute)
+	httpAddr.mu.Unlock()
+	if _, ok := httpAddr.PickPreviousOwner(moved); ok {
+		t.Fatal("should not pick previous owner after the window")
+	}
+}
+
+func TestHttpAddr_ReconfigureClients(t *testing.T) {
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.Set("http://localhost:8001", "http://localhost:8002", "http://localhost:8003")
+	ring := httpAddr.peers
+	old := httpAddr.HttpClients["http://localhost:8002"]
+
+	httpAddr.ReconfigureClients(ClientConfig{VerifyChecksum: true, RawTransport: true})
+
+	if httpAddr.peers != ring {
+		t.Fatal("ring should not be rebuilt")
+	}
+	client := httpAddr.HttpClients["http://localhost:8002"]
+	if client == old || !client.VerifyChecksum || !client.Raw {
+		t.Fatalf("client should be recreated with new config, got %+v", client)
+	}
+	if len(httpAddr.HttpClients) != 3 {
+		t.Fatalf("expected 3 clients, got %d", len(httpAddr.HttpClients))
+	}
+	if client.Timeout != defaultPeerTimeout {
+		t.Fatalf("expected default peer timeout, got %v", client.Timeout)
+	}
+	if client.MaxIdleConnsPerHost != defaultMaxIdleConnsPerHost || client.IdleConnTimeout != defaultIdleConnTimeout {
+		t.Fatalf("expected default connection pool, got %+v", client)
+	}
+
+	httpAddr.ReconfigureClients(ClientConfig{PeerTimeout: -1})
+	if client := httpAddr.HttpClients["http://localhost:8002"]; client.Timeout != 0 {
+		t.Fatalf("negative PeerTimeout should disable the timeout, got %v", client.Timeout)
+	}
+
+	httpAddr.ReconfigureClients(ClientConfig{MaxIdleConnsPerHost: 8, IdleConnTimeout: time.Second})
+	if client := httpAddr.HttpClients["http://localhost:8002"]; client.MaxIdleConnsPerHost != 8 || client.IdleConnTimeout != time.Second {
+		t.Fatalf("connection pool settings should be applied, got %+v", client)
+	}
+}
+
+func TestHttpAddr_ReconfigureClients_ClosesIdleConns(t *testing.T) {
+	var closed atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := proto.Marshal(&pb.Response{Value: []byte("630")})
+		w.Write(body)
+	}))
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.Set("http://localhost:8001", server.URL)
+	if err := httpAddr.HttpClients[server.URL].Get(&pb.Request{Group: "scores", Key: "Tom"}, &pb.Response{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 旧 HttpClient 被替换后，其空闲连接应被关闭而不是留在无人引用的连接池中
+	httpAddr.ReconfigureClients(ClientConfig{})
+	deadline := time.Now().Add(time.Second)
+	for closed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if closed.Load() != 1 {
+		t.Fatalf("expected the old client's idle connection to be closed, got %d", closed.Load())
+	}
+}
+
+func TestHttpAddr_PreferHealthy(t *testing.T) {
+	_ = createTestGroup("scores_health")
+
+	healthy := httptest.NewServer(setupTestRouter(NewHttpAddr("")))
+	defer healthy.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.Set(healthy.URL, down.URL)
+
+	// 找一个归属于故障节点的 key
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("health-key-%d", i)
+		if httpAddr.Candidates(key)[0] == down.URL {
+			break
+		}
+	}
+
+	req := &pb.Request{Group: "scores_health", Key: "Tom"}
+	for i := 0; i < 5; i++ {
+		httpAddr.HttpClients[down.URL].Get(req, &pb.Response{})
+		httpAddr.HttpClients[healthy.URL].Get(req, &pb.Response{})
+	}
+
+	stats := httpAddr.PeerStats()
+	if stats[down.URL].Health >= stats[healthy.URL].Health {
+		t.Fatalf("expected failing peer to have a lower health score, got %+v", stats)
+	}
+
+	if client, _ := pickClient(httpAddr, key); client.BaseURL != down.URL+defaultBasePath {
+		t.Fatal("without PreferHealthy routing should stay consistent")
+	}
+	httpAddr.PreferHealthy = true
+	if client, _ := pickClient(httpAddr, key); client.BaseURL != healthy.URL+defaultBasePath {
+		t.Fatalf("expected healthier peer, got %s", client.BaseURL)
+	}
+}
+
+func TestHttpAddr_ForwardedHops(t *testing.T) {
+	var loads, hops atomic.Int32
+	g := group.NewGroup("scores_hops", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			return []byte("value-" + key), nil
+		}))
+
+	// 三个节点共用进程内的同一个 Group 与本节点的 HttpAddr，每处理一个请求计一跳
+	var node *HttpAddr
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops.Add(1)
+		node.Handler().ServeHTTP(w, r)
+	})
+	var urls []string
+	for i := 0; i < 3; i++ {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+		urls = append(urls, server.URL)
+	}
+	node = NewHttpAddr(urls[0])
+	node.PreferHealthy = true
+	node.HealthCandidates = 3
+	node.PeerTimeout = 500 * time.Millisecond
+	node.Set(urls...)
+	g.RegisterPeers(node)
+
+	// 找一个不归本节点的 key，模拟另一个节点按 PreferHealthy 把它转发到了本节点
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("hop-key-%d", i)
+		if node.Candidates(key)[0] != urls[0] {
+			break
+		}
+	}
+	client := &httpclient.HttpClient{BaseURL: urls[0] + defaultBasePath}
+	out := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "scores_hops", Key: key}, out); err != nil {
+		t.Fatal(err)
+	}
+	if string(out.GetValue()) != "value-"+key {
+		t.Fatalf("unexpected value %q", out.GetValue())
+	}
+	if hops.Load() != 1 || loads.Load() != 1 {
+		t.Fatalf("forwarded request should be loaded locally in 1 hop, got %d hops and %d loads", hops.Load(), loads.Load())
+	}
+}
+
+func TestHttpAddr_RawTransport(t *testing.T) {
+	_ = createTestGroup("scores_raw")
+
+	server := httptest.NewServer(setupTestRouter(NewHttpAddr("")))
+	defer server.Close()
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.RawTransport = true
+	httpAddr.VerifyChecksum = true
+	httpAddr.Set(server.URL)
+
+	out := &pb.Response{}
+	if err := httpAddr.HttpClients[server.URL].Get(&pb.Request{Group: "scores_raw", Key: "Sam"}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out.GetValue()) != "567" {
+		t.Fatalf("expected '567', got '%s'", out.GetValue())
+	}
+}
+
 // ---------- 并发安全测试 ----------
 
 func TestHttpAddr_ConcurrentSet(t *testing.T) {
@@ -200,9 +621,8 @@ func TestServe_Success(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 
-	body, _ := io.ReadAll(w.Body)
-	if string(body) != "630" {
-		t.Fatalf("expected body '630', got '%s'", string(body))
+	if body := decodeBody(t, w.Body); body != "630" {
+		t.Fatalf("expected body '630', got '%s'", body)
 	}
 }
 
@@ -264,6 +684,62 @@ func TestServe_ContentType(t *testing.T) {
 	}
 }
 
+func TestServe_Put(t *testing.T) {
+	_ = createTestGroup("scores_put")
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	router := setupTestRouter(httpAddr)
+
+	body, _ := proto.Marshal(&pb.Response{Value: []byte("999")})
+	req, _ := http.NewRequest("PUT", "/_geecache/scores_put/Alice", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	// Alice 不在 db 中，只能从写入的缓存中读到
+	req, _ = http.NewRequest("GET", "/_geecache/scores_put/Alice", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	if got := decodeBody(t, w.Body); got != "999" {
+		t.Fatalf("expected body '999', got '%s'", got)
+	}
+}
+
+func TestServe_WithoutLoader(t *testing.T) {
+	group.NewGroup("push_only", 2<<10, nil, group.WithoutLoader())
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	router := setupTestRouter(httpAddr)
+
+	req, _ := http.NewRequest("GET", "/_geecache/push_only/Tom", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 before Set, got %d", w.Code)
+	}
+
+	group.GetGroup("push_only").Set("Tom", []byte("630"))
+
+	req, _ = http.NewRequest("GET", "/_geecache/push_only/Tom", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after Set, got %d", w.Code)
+	}
+	if got := decodeBody(t, w.Body); got != "630" {
+		t.Fatalf("expected body '630', got '%s'", got)
+	}
+}
+
 // ---------- Log 测试 ----------
 
 func TestHttpAddr_Log(t *testing.T) {
@@ -303,9 +779,8 @@ func TestIntegration_MultipleRequests(t *testing.T) {
 		}
 
 		if tc.status == http.StatusOK {
-			body, _ := io.ReadAll(w.Body)
-			if string(body) != tc.expected {
-				t.Fatalf("key %s: expected body '%s', got '%s'", tc.key, tc.expected, string(body))
+			if body := decodeBody(t, w.Body); body != tc.expected {
+				t.Fatalf("key %s: expected body '%s', got '%s'", tc.key, tc.expected, body)
 			}
 		}
 	}
@@ -453,6 +928,24 @@ func BenchmarkServe(b *testing.B) {
 	}
 }
 
+func BenchmarkServe_Raw(b *testing.B) {
+	groupName := "bench_scores_raw"
+	_ = createTestGroup(groupName)
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	router := setupTestRouter(httpAddr)
+
+	path := fmt.Sprintf("/_geecache/%s/Tom", groupName)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Accept", httpclient.RawContentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+	}
+}
+
 // ---------- 模拟分布式场景测试 ----------
 
 func TestDistributed_PeerSelection(t *testing.T) {
@@ -476,7 +969,7 @@ func TestDistributed_PeerSelection(t *testing.T) {
 	for _, key := range testKeys {
 		var selectedPeers []string
 		for _, node := range nodes {
-			peer, ok := node.PickPeer(key)
+			peer, ok := pickClient(node, key)
 			if ok {
 				selectedPeers = append(selectedPeers, peer.BaseURL)
 			}
@@ -512,6 +1005,112 @@ func TestHttpAddr_SinglePeer_Self(t *testing.T) {
 	}
 }
 
+func TestHttpAddr_HashSeed(t *testing.T) {
+	peers := []string{"http://localhost:8001", "http://localhost:8002", "http://localhost:8003"}
+	a := NewHttpAddr(peers[0])
+	a.HashSeed = []byte("cluster-secret")
+	a.Set(peers...)
+	b := NewHttpAddr(peers[0])
+	b.HashSeed = []byte("cluster-secret")
+	b.Set(peers...)
+	plain := NewHttpAddr(peers[0])
+	plain.Set(peers...)
+
+	differs := false
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		if a.peers.Get(key) != b.peers.Get(key) {
+			t.Fatalf("nodes with the same seed disagree on %s", key)
+		}
+		if a.peers.Get(key) != plain.peers.Get(key) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Fatal("seeded ring should place keys differently from the default ring")
+	}
+	if sub := a.AddPath("/_other/"); string(sub.HashSeed) != "cluster-secret" {
+		t.Fatal("AddPath should inherit HashSeed")
+	}
+}
+
+func TestHttpAddr_HashFunc(t *testing.T) {
+	peers := []string{"http://localhost:8001", "http://localhost:8002", "http://localhost:8003"}
+	fnv32 := func(data []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(data)
+		return h.Sum32()
+	}
+	a := NewHttpAddr(peers[0])
+	a.HashFunc = fnv32
+	a.HashSeed = []byte("ignored")
+	a.Set(peers...)
+	ring := consistenthash.New(consistenthash.DefaultReplicas, fnv32)
+	ring.AddKeys(peers...)
+	if a.RingDigest() != ring.Digest() {
+		t.Fatal("HashFunc should be used for the ring and take precedence over HashSeed")
+	}
+
+	plain := NewHttpAddr(peers[0])
+	plain.Set(peers...)
+	ring = consistenthash.New(consistenthash.DefaultReplicas, nil)
+	ring.AddKeys(peers...)
+	if plain.RingDigest() != ring.Digest() {
+		t.Fatal("nil HashFunc should keep the crc32 default")
+	}
+	if sub := a.AddPath("/_other/"); sub.HashFunc == nil {
+		t.Fatal("AddPath should inherit HashFunc")
+	}
+}
+
+func TestHttpAddr_Replicas(t *testing.T) {
+	peers := []string{"http://localhost:8001", "http://localhost:8002", "http://localhost:8003"}
+	def := NewHttpAddr(peers[0])
+	def.Set(peers...)
+	explicit := NewHttpAddr(peers[0])
+	explicit.Replicas = consistenthash.DefaultReplicas
+	explicit.Set(peers...)
+	if def.RingDigest() != explicit.RingDigest() {
+		t.Fatal("zero Replicas should use the default")
+	}
+
+	few := NewHttpAddr(peers[0])
+	few.Replicas = 3
+	few.Set(peers...)
+	if few.RingDigest() == def.RingDigest() {
+		t.Fatal("Replicas should change the ring")
+	}
+	// 虚拟节点很少时每个 key 仍归属某个节点
+	for i := 0; i < 100; i++ {
+		if few.peers.Get(fmt.Sprintf("key%d", i)) == "" {
+			t.Fatal("every key should have an owner")
+		}
+	}
+	if sub := few.AddPath("/_other/"); sub.Replicas != 3 {
+		t.Fatal("AddPath should inherit Replicas")
+	}
+}
+
+func TestHttpAddr_RingDigest(t *testing.T) {
+	peers := []string{"http://localhost:8001", "http://localhost:8002", "http://localhost:8003"}
+	a := NewHttpAddr(peers[0])
+	a.Set(peers...)
+	b := NewHttpAddr(peers[1])
+	b.Set(peers[2], peers[1], peers[0])
+	if a.RingDigest() != b.RingDigest() {
+		t.Fatal("nodes with the same peer set should have the same digest")
+	}
+
+	b.Set(peers[:2]...)
+	if a.RingDigest() == b.RingDigest() {
+		t.Fatal("nodes with different peer sets should have different digests")
+	}
+
+	if NewHttpAddr(peers[0]).RingDigest() == "" {
+		t.Fatal("empty ring should still have a digest")
+	}
+}
+
 func TestHttpAddr_UpdatePeers(t *testing.T) {
 	httpAddr := NewHttpAddr("http://localhost:8001")
 
@@ -533,6 +1132,48 @@ func TestHttpAddr_UpdatePeers(t *testing.T) {
 	}
 }
 
+// ---------- 下线交接测试 ----------
+
+func TestHttpAddr_ShutdownHandoff(t *testing.T) {
+	g := createTestGroup("handoff_test")
+
+	var mu sync.Mutex
+	received := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", r.Method)
+		}
+		// 只统计本测试 Group 的条目，其他测试留下的 Group 也会被交接
+		if strings.HasPrefix(r.URL.Path, defaultBasePath+"handoff_test/") {
+			mu.Lock()
+			received[r.URL.Path] = true
+			mu.Unlock()
+		}
+	}))
+	defer server.Close()
+
+	host := "http://localhost:8001"
+	httpAddr := NewHttpAddr(host)
+	httpAddr.Set(host, server.URL)
+	httpAddr.HandoffOnShutdown = true
+
+	owned := 0
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("handoff-key-%d", i)
+		g.Set(key, []byte("v"))
+		if httpAddr.Candidates(key)[0] == host {
+			owned++
+		}
+	}
+
+	if err := httpAddr.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected handoff error: %v", err)
+	}
+	if len(received) != owned {
+		t.Fatalf("expected %d handed off keys, got %d", owned, len(received))
+	}
+}
+
 // ---------- 超时和错误处理测试 ----------
 
 func TestServe_Timeout(t *testing.T) {
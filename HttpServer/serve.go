@@ -1,13 +1,27 @@
 package httpserver
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	cache "geecache/Cache"
 	group "geecache/Group"
+	httpclient "geecache/HttpClient"
 	pb "geecache/geecachepb"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -15,50 +29,586 @@ func (p *HttpAddr) Log(format string, v ...interface{}) {
 	log.Printf("[Serve on %s] %s", p.Path, fmt.Sprintf(format, v...))
 }
 
-func (p *HttpAddr) Serve(c *gin.Context) {
-	if !strings.HasPrefix(c.Request.URL.Path, p.Path) {
-		panic(fmt.Sprintf("GeeCache get unexcepted path : %s", c.Request.URL.Path))
+// Handler 返回基于标准库 net/http 的处理器，不依赖 gin
+func (p *HttpAddr) Handler() http.Handler {
+	return p
+}
+
+func (p *HttpAddr) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if sub := p.route(r.URL.Path); sub != p {
+		sub.ServeHTTP(w, r)
+		return
 	}
-	p.Log("Received %s request: %s", c.Request.Method, c.Request.URL.Path)
+	if !strings.HasPrefix(r.URL.Path, p.Path) {
+		panic(fmt.Sprintf("GeeCache get unexcepted path : %s", r.URL.Path))
+	}
+	p.Log("Received %s request: %s", r.Method, r.URL.Path)
 
-	parts := strings.SplitN(c.Request.URL.Path[len(p.Path):], "/", 2)
+	// 按转义后的路径切分，group 与 key 中编码过的 '/' 不会被当作分隔符
+	parts := strings.SplitN(r.URL.EscapedPath()[len(p.Path):], "/", 2)
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			httpError(
+				w,
+				pb.ErrorCode_BAD_REQUEST,
+				err.Error(),
+			)
+			return
+		}
+		parts[i] = unescaped
+	}
+	// GET Path/health 健康检查，下线期间返回 503，让其他节点尽快把本节点移出哈希环
+	if len(parts) == 1 && parts[0] == httpclient.HealthPath && r.Method == http.MethodGet && !r.URL.Query().Has("key") {
+		if p.closing.Load() {
+			httpError(
+				w,
+				pb.ErrorCode_UNAVAILABLE,
+				"Shutting Down",
+			)
+			return
+		}
+		w.WriteHeader(200)
+		return
+	}
+	// 健康检查之外的请求都需要携带 Token
+	if !p.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="geecache"`)
+		httpError(
+			w,
+			pb.ErrorCode_UNAUTHENTICATED,
+			"Unauthorized",
+		)
+		return
+	}
+	// Path/_admin/... 管理接口
+	if len(parts) == 2 && parts[0] == adminPath {
+		p.serveAdmin(w, r, parts[1])
+		return
+	}
+	// POST Path/GroupName 批量写入
+	if r.Method == http.MethodPost && len(parts) == 1 {
+		p.serveBatchSet(w, r, parts[0])
+		return
+	}
+	// GET Path/GroupName?key=a&key=b 流式批量读取
+	if r.Method == http.MethodGet && len(parts) == 1 && r.URL.Query().Has("key") {
+		p.serveBatchGet(w, r, parts[0])
+		return
+	}
+	if len(parts) == 1 && r.Method != http.MethodGet {
+		methodNotAllowed(w, "GET, POST")
+		return
+	}
 	if len(parts) != 2 {
-		c.String(
-			400,
+		httpError(
+			w,
+			pb.ErrorCode_BAD_REQUEST,
 			"Bad Request",
 		)
 		return
 	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		methodNotAllowed(w, "GET, HEAD, PUT, DELETE")
+		return
+	}
 	// Path/GroupName/Key
 	groupName := parts[0]
 	key := parts[1]
 
-	group := group.GetGroup(groupName)
-	if group == nil {
-		c.String(
-			404,
+	g := group.GetGroup(groupName)
+	if g == nil {
+		httpError(
+			w,
+			pb.ErrorCode_GROUP_NOT_FOUND,
 			"Group Not Found",
 		)
 		return
 	}
 
-	bv, err := group.Get(key)
+	if r.Method == http.MethodPut {
+		body, err := p.readBody(w, r)
+		if err != nil {
+			httpError(
+				w,
+				bodyErrorCode(err),
+				err.Error(),
+			)
+			return
+		}
+		req := &pb.Response{}
+		if err = proto.Unmarshal(body, req); err != nil {
+			httpError(
+				w,
+				pb.ErrorCode_BAD_REQUEST,
+				err.Error(),
+			)
+			return
+		}
+		if !g.Set(key, req.GetValue()) {
+			httpError(
+				w,
+				pb.ErrorCode_INSUFFICIENT_STORAGE,
+				"Insufficient Storage",
+			)
+			return
+		}
+		w.WriteHeader(200)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		g.RemoveLocal(key)
+		w.WriteHeader(200)
+		return
+	}
+
+	bv, err := g.GetContext(loadContext(r), key)
+	var rejected *group.RejectedError
+	if errors.As(err, &rejected) {
+		w.Header().Set("Retry-After", retryAfter(rejected.RetryAfter))
+		httpError(
+			w,
+			pb.ErrorCode_UNAVAILABLE,
+			err.Error(),
+		)
+		return
+	}
+	// 数据源中不存在的 key 是正常的未命中，返回 404 而不是 500，避免触发 5xx 告警
+	if errors.Is(err, group.ErrNotFound) {
+		httpError(
+			w,
+			pb.ErrorCode_KEY_NOT_FOUND,
+			err.Error(),
+		)
+		return
+	}
+	if errors.Is(err, group.ErrCacheMiss) {
+		httpError(
+			w,
+			pb.ErrorCode_CACHE_MISS,
+			err.Error(),
+		)
+		return
+	}
 	if err != nil {
-		c.String(
-			500,
+		httpError(
+			w,
+			pb.ErrorCode_INTERNAL,
 			err.Error(),
 		)
 		return
 	}
 
-	body, err := proto.Marshal(&pb.Response{Value: bv.ByteSlice()})
+	// Range 请求只返回值的一部分，响应 206 Partial Content；无法解析或包含多个区间的 Range 被忽略
+	status := http.StatusOK
+	w.Header().Set("Accept-Ranges", "bytes")
+	if rng := r.Header.Get("Range"); rng != "" {
+		if bv.Encoding() != "" {
+			// 区间按解压后的值计算
+			bv = bv.SliceFrom(0)
+		}
+		size := bv.Len()
+		start, end, err := parseRange(rng, size)
+		if err == errRangeNotSatisfiable {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			httpError(
+				w,
+				pb.ErrorCode_RANGE_NOT_SATISFIABLE,
+				err.Error(),
+			)
+			return
+		}
+		if err == nil {
+			bv, _ = bv.Slice(start, end)
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+			status = http.StatusPartialContent
+		}
+	}
+
+	// Accept: application/json 时返回 JSON，便于 curl、浏览器与没有 protobuf 工具链的客户端使用
+	if acceptsJSON(r) {
+		body, err := json.Marshal(jsonResponse{Group: groupName, Key: key, Value: bv.ByteSlice()})
+		if err != nil {
+			httpError(
+				w,
+				pb.ErrorCode_INTERNAL,
+				err.Error(),
+			)
+			return
+		}
+		p.write(w, r, status, "application/json", bytes.NewReader(body), len(body), true)
+		return
+	}
+
+	// 校验和只在客户端要求时计算，见 httpclient.WantChecksumHeader
+	wantChecksum := r.Header.Get(httpclient.WantChecksumHeader) != ""
+
+	// 值已按客户端接受的编码压缩存储时直接透传，省去解压与再压缩
+	if r.Header.Get("Accept") == httpclient.RawContentType && bv.Encoding() == cache.EncodingGzip && acceptsGzip(r) {
+		encoded, sum := bv.Encoded()
+		if wantChecksum {
+			w.Header().Set(httpclient.ChecksumHeader, sum)
+		}
+		w.Header().Set("Content-Type", httpclient.RawContentType)
+		w.Header().Set("Content-Encoding", cache.EncodingGzip)
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.Header().Set("Content-Length", strconv.Itoa(bv.Len()))
+		w.WriteHeader(status)
+		io.Copy(w, encoded)
+		return
+	}
+
+	// 未压缩存储的值直接从 ByteView 流式写出，不复制底层字节
+	if r.Header.Get("Accept") == httpclient.RawContentType && bv.Encoding() == "" {
+		if wantChecksum {
+			h := sha256.New()
+			bv.WriteTo(h)
+			w.Header().Set(httpclient.ChecksumHeader, hex.EncodeToString(h.Sum(nil)))
+		}
+		p.write(w, r, status, httpclient.RawContentType, bv, bv.Len(), true)
+		return
+	}
+
+	value := bv.ByteSlice()
+	if r.Header.Get("Accept") == httpclient.RawContentType {
+		if wantChecksum {
+			w.Header().Set(httpclient.ChecksumHeader, httpclient.Checksum(value))
+		}
+		p.write(w, r, status, httpclient.RawContentType, bytes.NewReader(value), len(value), true)
+		return
+	}
+
+	body, err := proto.Marshal(p.response(value, bv.Meta()))
 	if err != nil {
-		c.String(
-			500,
+		httpError(
+			w,
+			pb.ErrorCode_INTERNAL,
 			err.Error(),
 		)
 		return
 	}
-	c.Header("Content-Type", "application/octet-stream")
-	c.Data(200, "application/octet-stream", body)
+	if wantChecksum {
+		w.Header().Set(httpclient.ChecksumHeader, httpclient.Checksum(value))
+	}
+	p.write(w, r, status, "application/octet-stream", bytes.NewReader(body), len(body), p.GzipProto)
+}
+
+// authorized 判断请求是否携带了与 Token 一致的 Authorization: Bearer 头，Token 为空时不校验。
+// 使用常数时间比较，避免通过响应耗时逐字节猜出 Token
+func (p *HttpAddr) authorized(r *http.Request) bool {
+	if p.Token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(p.Token)) == 1
+}
+
+// jsonResponse 是 JSON 模式的响应体，Value 按 encoding/json 的约定编码为 base64
+type jsonResponse struct {
+	Group string `json:"group"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// response 构造携带元数据的 pb.Response，本地加载的值以本节点地址作为来源节点。
+// 裸传输模式不携带元数据。
+func (p *HttpAddr) response(value []byte, meta cache.Meta) *pb.Response {
+	res := &pb.Response{Value: value, Node: meta.Node, Version: meta.Version}
+	if res.Node == "" {
+		res.Node = p.self()
+	}
+	if !meta.CreatedAt.IsZero() {
+		res.CreatedAt = meta.CreatedAt.UnixNano()
+	}
+	return res
+}
+
+// retryAfter 将等待时间向上取整为 Retry-After 所需的秒数，至少为 1
+func retryAfter(d time.Duration) string {
+	secs := int64((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return strconv.FormatInt(secs, 10)
+}
+
+// serveBatchGet 并发加载查询参数中的全部 key，每完成一个就写出一帧 pb.BatchGetResult 并立即刷新。
+// 同时加载的 key 数不超过 BatchConcurrency，客户端断开后不再启动新的加载
+func (p *HttpAddr) serveBatchGet(w http.ResponseWriter, r *http.Request, groupName string) {
+	g := group.GetGroup(groupName)
+	if g == nil {
+		httpError(
+			w,
+			pb.ErrorCode_GROUP_NOT_FOUND,
+			"Group Not Found",
+		)
+		return
+	}
+
+	keys := r.URL.Query()["key"]
+	ctx := loadContext(r)
+	concurrency := p.BatchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+	// 缓冲足够大，请求结束后仍在进行的加载也能写入并退出
+	results := make(chan *pb.BatchGetResult, len(keys))
+	go func() {
+		for _, key := range keys {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(key string) {
+				defer func() { <-sem }()
+				res := &pb.BatchGetResult{Key: key}
+				if bv, err := g.GetContext(ctx, key); err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Value = bv.ByteSlice()
+				}
+				results <- res
+			}(key)
+		}
+	}()
+
+	w.Header().Set("Content-Type", httpclient.StreamContentType)
+	w.WriteHeader(200)
+	flusher, _ := w.(http.Flusher)
+	for range keys {
+		var res *pb.BatchGetResult
+		select {
+		case res = <-results:
+		case <-ctx.Done():
+			return
+		}
+		if err := httpclient.WriteFrame(w, res); err != nil {
+			p.Log("Failed to write batch frame: %v", err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// serveBatchSet 解码 pb.BatchSetRequest，逐条写入 Group，返回写入条数
+func (p *HttpAddr) serveBatchSet(w http.ResponseWriter, r *http.Request, groupName string) {
+	g := group.GetGroup(groupName)
+	if g == nil {
+		httpError(
+			w,
+			pb.ErrorCode_GROUP_NOT_FOUND,
+			"Group Not Found",
+		)
+		return
+	}
+
+	body, err := p.readBody(w, r)
+	if err != nil {
+		httpError(
+			w,
+			bodyErrorCode(err),
+			err.Error(),
+		)
+		return
+	}
+	req := &pb.BatchSetRequest{}
+	if err = proto.Unmarshal(body, req); err != nil {
+		httpError(
+			w,
+			pb.ErrorCode_BAD_REQUEST,
+			err.Error(),
+		)
+		return
+	}
+	var stored int64
+	for _, entry := range req.GetEntries() {
+		if g.Set(entry.GetKey(), entry.GetValue()) {
+			stored++
+		}
+	}
+
+	body, err = proto.Marshal(&pb.BatchSetResponse{Stored: stored})
+	if err != nil {
+		httpError(
+			w,
+			pb.ErrorCode_INTERNAL,
+			err.Error(),
+		)
+		return
+	}
+	p.write(w, r, http.StatusOK, "application/octet-stream", bytes.NewReader(body), len(body), false)
+}
+
+// write 写出 200 响应。compressible 为 true、响应体不小于 GzipThreshold 且客户端接受 gzip 时压缩响应体
+func (p *HttpAddr) write(w http.ResponseWriter, r *http.Request, status int, contentType string, body io.WriterTo, size int, compressible bool) {
+	w.Header().Set("Content-Type", contentType)
+	// 206 响应的区间针对未压缩的值，不能再压缩
+	if compressible && status == http.StatusOK && p.GzipThreshold > 0 && size >= p.GzipThreshold && acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+		w.WriteHeader(status)
+		zw := gzip.NewWriter(w)
+		body.WriteTo(zw)
+		zw.Close()
+		return
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(size))
+	w.WriteHeader(status)
+	body.WriteTo(w)
+}
+
+// errRangeNotSatisfiable 表示 Range 请求的区间完全落在值之外
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// parseRange 解析单个区间的 Range 请求头（"bytes=0-99"、"bytes=100-"、"bytes=-100"），
+// 返回值长度为 size 时的 [start, end) 区间。区间落在值之外时返回 errRangeNotSatisfiable；
+// 其他错误表示应忽略该请求头并返回完整的值。
+func parseRange(header string, size int) (int, int, error) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, fmt.Errorf("unsupported range %q", header)
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q", header)
+	}
+	if first == "" {
+		// 后缀区间：最后 n 个字节
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("invalid range %q", header)
+		}
+		if n == 0 {
+			return 0, 0, errRangeNotSatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, nil
+	}
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", header)
+	}
+	end := size
+	if last != "" {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < start {
+			return 0, 0, fmt.Errorf("invalid range %q", header)
+		}
+		if n+1 < end {
+			end = n + 1
+		}
+	}
+	if start >= size {
+		return 0, 0, errRangeNotSatisfiable
+	}
+	return start, end, nil
+}
+
+// loadContext 返回加载 r 请求的 key 所用的 ctx：随请求取消，来自其他节点的请求标记为 group.Forwarded，
+// 在本节点加载而不再转发
+func loadContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if r.Header.Get(httpclient.ForwardedHeader) != "" {
+		ctx = group.Forwarded(ctx)
+	}
+	return ctx
+}
+
+// acceptsJSON 判断 Accept 中是否列出了 application/json（忽略参数，q=0 表示拒绝）
+func acceptsJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(mediaType) != "application/json" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0"
+	}
+	return false
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.TrimSpace(coding) != "gzip" {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0"
+	}
+	return false
+}
+
+// methodNotAllowed 返回 405 与路径支持的方法
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	httpError(
+		w,
+		pb.ErrorCode_METHOD_NOT_ALLOWED,
+		"Method Not Allowed",
+	)
+}
+
+// readBody 读取请求体，超过 MaxBodyBytes 时返回 *http.MaxBytesError
+func (p *HttpAddr) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	limit := p.MaxBodyBytes
+	if limit == 0 {
+		limit = defaultMaxBodyBytes
+	}
+	if limit < 0 {
+		return io.ReadAll(r.Body)
+	}
+	return io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+}
+
+// bodyErrorCode 返回读取请求体失败对应的错误类别，超过大小上限时为 PAYLOAD_TOO_LARGE
+func bodyErrorCode(err error) pb.ErrorCode {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return pb.ErrorCode_PAYLOAD_TOO_LARGE
+	}
+	return pb.ErrorCode_BAD_REQUEST
+}
+
+// httpError 以 pb.Error 返回错误，HTTP 状态码由 code 决定，客户端可据 code 区分错误类别
+func httpError(w http.ResponseWriter, code pb.ErrorCode, msg string) {
+	body, _ := proto.Marshal(&pb.Error{Code: code, Message: msg})
+	w.Header().Set("Content-Type", httpclient.ErrorContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusOf(code))
+	w.Write(body)
+}
+
+// statusOf 返回错误类别对应的 HTTP 状态码
+func statusOf(code pb.ErrorCode) int {
+	switch code {
+	case pb.ErrorCode_BAD_REQUEST:
+		return http.StatusBadRequest
+	case pb.ErrorCode_GROUP_NOT_FOUND, pb.ErrorCode_KEY_NOT_FOUND, pb.ErrorCode_CACHE_MISS:
+		return http.StatusNotFound
+	case pb.ErrorCode_UNAVAILABLE:
+		return http.StatusServiceUnavailable
+	case pb.ErrorCode_INSUFFICIENT_STORAGE:
+		return http.StatusInsufficientStorage
+	case pb.ErrorCode_METHOD_NOT_ALLOWED:
+		return http.StatusMethodNotAllowed
+	case pb.ErrorCode_RANGE_NOT_SATISFIABLE:
+		return http.StatusRequestedRangeNotSatisfiable
+	case pb.ErrorCode_UNAUTHENTICATED:
+		return http.StatusUnauthorized
+	case pb.ErrorCode_PERMISSION_DENIED:
+		return http.StatusForbidden
+	case pb.ErrorCode_PAYLOAD_TOO_LARGE:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
 }
@@ -0,0 +1,1053 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/json"
+	"errors"
+	"fmt"
+	cache "geecache/Cache"
+	callbackfunc "geecache/CallbackFunc"
+	group "geecache/Group"
+	httpclient "geecache/HttpClient"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ---------- 标准库 Handler 测试（不依赖 gin） ----------
+
+func TestHandler_StdLib(t *testing.T) {
+	group.NewGroup("stdlib_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	mux := http.NewServeMux()
+	mux.Handle(httpAddr.Path, httpAddr.Handler())
+
+	req, _ := http.NewRequest("GET", "/_geecache/stdlib_test/Tom", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	body, _ := io.ReadAll(w.Body)
+	res := &pb.Response{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		t.Fatalf("decoding response body: %v", err)
+	}
+	if string(res.GetValue()) != "value-Tom" {
+		t.Fatalf("expected 'value-Tom', got '%s'", res.GetValue())
+	}
+
+	req, _ = http.NewRequest("GET", "/_geecache/missing_group/Tom", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestHandler_Methods(t *testing.T) {
+	g := group.NewGroup("methods_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+	httpAddr := NewHttpAddr("http://localhost:8001")
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		httpAddr.ServeHTTP(w, req)
+		return w
+	}
+
+	body, _ := proto.Marshal(&pb.Response{Value: []byte("put-value")})
+	if w := do(http.MethodPut, "/_geecache/methods_test/k", body); w.Code != http.StatusOK {
+		t.Fatalf("PUT: expected 200, got %d", w.Code)
+	}
+	if v, _ := g.Get("k"); v.String() != "put-value" {
+		t.Fatalf("PUT should set the value, got %q", v.String())
+	}
+	if w := do(http.MethodDelete, "/_geecache/methods_test/k", nil); w.Code != http.StatusOK {
+		t.Fatalf("DELETE: expected 200, got %d", w.Code)
+	}
+	if v, _ := g.Get("k"); v.String() != "value-k" {
+		t.Fatalf("DELETE should evict the value, got %q", v.String())
+	}
+
+	for _, tc := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodPatch, "/_geecache/methods_test/k", "GET, HEAD, PUT, DELETE"},
+		{http.MethodPost, "/_geecache/methods_test/k", "GET, HEAD, PUT, DELETE"},
+		{http.MethodDelete, "/_geecache/methods_test", "GET, POST"},
+	} {
+		w := do(tc.method, tc.path, nil)
+		if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != tc.allow {
+			t.Errorf("%s %s: expected 405 with Allow %q, got %d %q", tc.method, tc.path, tc.allow, w.Code, w.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	group.NewGroup("handler_errors_test", 2<<10, nil, group.WithoutLoader())
+	group.NewGroup("handler_not_found_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("%s: %w", key, group.ErrNotFound)
+		}))
+	srv := httptest.NewServer(NewHttpAddr("").Handler())
+	defer srv.Close()
+	client := &httpclient.HttpClient{BaseURL: srv.URL + "/_geecache/"}
+
+	tests := []struct {
+		group  string
+		status int
+		code   pb.ErrorCode
+	}{
+		{"no_such_group", http.StatusNotFound, pb.ErrorCode_GROUP_NOT_FOUND},
+		{"handler_errors_test", http.StatusNotFound, pb.ErrorCode_CACHE_MISS},
+		{"handler_not_found_test", http.StatusNotFound, pb.ErrorCode_KEY_NOT_FOUND},
+	}
+	for _, tt := range tests {
+		err := client.Get(&pb.Request{Group: tt.group, Key: "k"}, &pb.Response{})
+		var e *httpclient.Error
+		if !errors.As(err, &e) || e.Status != tt.status || e.Code != tt.code {
+			t.Errorf("%s: expected %d %s, got %v", tt.group, tt.status, tt.code, err)
+		}
+	}
+
+	// 客户端将 404 还原为 group 包的哨兵错误
+	err := client.Get(&pb.Request{Group: "handler_not_found_test", Key: "k"}, &pb.Response{})
+	if !errors.Is(err, group.ErrNotFound) || errors.Is(err, group.ErrCacheMiss) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestHandler_Token(t *testing.T) {
+	group.NewGroup("token_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+	peer := NewHttpAddr("")
+	peer.Token = "s3cret"
+	srv := httptest.NewServer(peer.Handler())
+	defer srv.Close()
+	req := &pb.Request{Group: "token_test", Key: "k"}
+
+	for _, token := range []string{"", "wrong"} {
+		client := &httpclient.HttpClient{BaseURL: srv.URL + "/_geecache/", Token: token}
+		err := client.Get(req, &pb.Response{})
+		var e *httpclient.Error
+		if !errors.As(err, &e) || e.Status != http.StatusUnauthorized || e.Code != pb.ErrorCode_UNAUTHENTICATED {
+			t.Errorf("token %q: expected 401, got %v", token, err)
+		}
+		if _, err := client.SetBatch("token_test", map[string][]byte{"k": []byte("v")}); err == nil {
+			t.Errorf("token %q: batch writes should be rejected", token)
+		}
+	}
+
+	// 健康检查不需要 Token
+	if err := (&httpclient.HttpClient{BaseURL: srv.URL + "/_geecache/"}).Ping(context.Background()); err != nil {
+		t.Fatalf("health check should not require a token, got %v", err)
+	}
+
+	// HttpAddr 为发往其他节点的请求附加 Token
+	httpAddr := NewHttpAddr("http://self")
+	httpAddr.Token = "s3cret"
+	httpAddr.Set(srv.URL)
+	out := &pb.Response{}
+	if err := httpAddr.HttpClients[srv.URL].Get(req, out); err != nil || string(out.GetValue()) != "value-k" {
+		t.Fatalf("expected value with a matching token, got %q, %v", out.GetValue(), err)
+	}
+	if n, err := httpAddr.HttpClients[srv.URL].SetBatch("token_test", map[string][]byte{"k2": []byte("v")}); err != nil || n != 1 {
+		t.Fatalf("batch writes with a matching token should succeed, got %d, %v", n, err)
+	}
+}
+
+func TestHandler_Admin(t *testing.T) {
+	g := group.NewGroup("admin_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+	g.Get("a")
+	g.Get("b")
+	peer := NewHttpAddr("")
+	handler := peer.Handler()
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 管理接口的前缀不能用作 Group 名
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("creating a group named _admin should panic")
+			}
+		}()
+		group.NewGroup(adminPath, 2<<10, nil, group.WithoutLoader())
+	}()
+
+	// 未配置 Token 时管理接口不可用
+	if w := do(http.MethodGet, "/_geecache/_admin/keys?group=admin_test", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("admin endpoints should require a configured token, got %d", w.Code)
+	}
+
+	peer.Token = "s3cret"
+	if w := do(http.MethodGet, "/_geecache/_admin/keys?group=admin_test", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with a wrong token, got %d", w.Code)
+	}
+	w := do(http.MethodGet, "/_geecache/_admin/keys?group=admin_test", "s3cret")
+	var keys struct {
+		Group string
+		Keys  []string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &keys); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if keys.Group != "admin_test" || len(keys.Keys) != 2 || keys.Keys[0] != "b" {
+		t.Fatalf("expected keys [b a], got %+v", keys)
+	}
+
+	tests := []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodPost, "/_geecache/_admin/keys?group=admin_test", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/_geecache/_admin/flush?group=admin_test", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/_geecache/_admin/keys", http.StatusBadRequest},
+		{http.MethodGet, "/_geecache/_admin/keys?group=no_such_group", http.StatusNotFound},
+		{http.MethodGet, "/_geecache/_admin/other?group=admin_test", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if w := do(tt.method, tt.target, "s3cret"); w.Code != tt.status {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.status, w.Code)
+		}
+	}
+
+	w = do(http.MethodPost, "/_geecache/_admin/flush?group=admin_test", "s3cret")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"flushed":2`) || g.Len() != 0 {
+		t.Fatalf("flush should clear the cache, got %d %s with %d entries", w.Code, w.Body.String(), g.Len())
+	}
+}
+
+func TestHandler_Range(t *testing.T) {
+	group.NewGroup("range_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("0123456789"), nil
+		}))
+	handler := NewHttpAddr("").Handler()
+
+	tests := []struct {
+		rng          string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"", http.StatusOK, "0123456789", ""},
+		{"bytes=2-4", http.StatusPartialContent, "234", "bytes 2-4/10"},
+		{"bytes=7-", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-3", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=8-100", http.StatusPartialContent, "89", "bytes 8-9/10"},
+		{"bytes=0-1,4-5", http.StatusOK, "0123456789", ""},
+		{"bytes=10-", http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/_geecache/range_test/k", nil)
+		req.Header.Set("Accept", httpclient.RawContentType)
+		if tt.rng != "" {
+			req.Header.Set("Range", tt.rng)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.status || w.Header().Get("Content-Range") != tt.contentRange {
+			t.Errorf("%q: got %d with Content-Range %q", tt.rng, w.Code, w.Header().Get("Content-Range"))
+			continue
+		}
+		if tt.status != http.StatusRequestedRangeNotSatisfiable && w.Body.String() != tt.body {
+			t.Errorf("%q: expected body %q, got %q", tt.rng, tt.body, w.Body.String())
+		}
+	}
+
+	// protobuf 响应中的 value 同样被切片
+	req := httptest.NewRequest(http.MethodGet, "/_geecache/range_test/k", nil)
+	req.Header.Set("Range", "bytes=0-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	res := &pb.Response{}
+	if err := proto.Unmarshal(w.Body.Bytes(), res); err != nil || w.Code != http.StatusPartialContent || string(res.GetValue()) != "01" {
+		t.Fatalf("expected 206 with value \"01\", got %d %q %v", w.Code, res.GetValue(), err)
+	}
+}
+
+func TestHandler_JSON(t *testing.T) {
+	group.NewGroup("json_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte{0, 1, 'v', 0xff}, nil
+		}))
+	handler := NewHttpAddr("").Handler()
+
+	req := httptest.NewRequest(http.MethodGet, "/_geecache/json_test/k", nil)
+	req.Header.Set("Accept", "text/plain, application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected JSON response, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var res struct {
+		Group string
+		Key   string
+		Value []byte
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Group != "json_test" || res.Key != "k" || !bytes.Equal(res.Value, []byte{0, 1, 'v', 0xff}) {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"value":"AAF2/w=="`) {
+		t.Fatalf("value should be base64 encoded, got %s", w.Body.String())
+	}
+
+	// 未声明 JSON 时保持 protobuf
+	req = httptest.NewRequest(http.MethodGet, "/_geecache/json_test/k", nil)
+	req.Header.Set("Accept", "application/json;q=0")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Fatalf("expected protobuf response, got %q", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestHandler_Metadata(t *testing.T) {
+	group.NewGroup("metadata_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	req, _ := http.NewRequest("GET", "/_geecache/metadata_test/Tom", nil)
+	w := httptest.NewRecorder()
+	httpAddr.ServeHTTP(w, req)
+
+	res := &pb.Response{}
+	if err := proto.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("decoding response body: %v", err)
+	}
+	if res.GetNode() != httpAddr.Host || res.GetVersion() == 0 || res.GetCreatedAt() == 0 {
+		t.Fatalf("response should carry metadata, got %+v", res)
+	}
+}
+
+// ---------- gzip 测试 ----------
+
+func TestHandler_Gzip(t *testing.T) {
+	large := strings.Repeat("geecache ", 200)
+	group.NewGroup("gzip_test", 2<<20, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if key == "large" {
+				return []byte(large), nil
+			}
+			return []byte("small"), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.GzipThreshold = 1024
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/_geecache/gzip_test/"+key, nil)
+		req.Header.Set("Accept", httpclient.RawContentType)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		httpAddr.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("large")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("expected large raw response to be gzipped")
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("unexpected gzip error: %v", err)
+	}
+	body, _ := io.ReadAll(zr)
+	if string(body) != large {
+		t.Fatal("decompressed body does not match value")
+	}
+
+	if w := get("small"); w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("small response should not be compressed")
+	}
+
+	// 默认不压缩 protobuf 响应
+	req, _ := http.NewRequest("GET", "/_geecache/gzip_test/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	httpAddr.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("proto response should not be compressed by default")
+	}
+
+	// HttpClient 通过 Transport 透明解压
+	server := httptest.NewServer(httpAddr)
+	defer server.Close()
+	client := &httpclient.HttpClient{BaseURL: server.URL + "/_geecache/", Raw: true, VerifyChecksum: true}
+	out := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "gzip_test", Key: "large"}, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out.GetValue()) != large {
+		t.Fatal("client should receive the decompressed value")
+	}
+}
+
+func TestHandler_GzipPassThrough(t *testing.T) {
+	large := strings.Repeat("geecache ", 200)
+	store := cache.NewCache(0, nil)
+	store.Add("k", cache.NewGzipByteView([]byte(large)))
+	group.NewGroupWithCache("gzip_pass_test", store, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("%s not exist", key)
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	req, _ := http.NewRequest("GET", "/_geecache/gzip_pass_test/k", nil)
+	req.Header.Set("Accept", httpclient.RawContentType)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	httpAddr.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("compressed value should be passed through as gzip")
+	}
+	stored, _ := store.Peek("k")
+	if w.Body.Len() != stored.Len() {
+		t.Fatalf("body should be the stored compressed bytes (%d), got %d bytes", stored.Len(), w.Body.Len())
+	}
+
+	// 经 HttpClient 读取时透明解压并通过校验
+	srv := httptest.NewServer(httpAddr.Handler())
+	defer srv.Close()
+	client := &httpclient.HttpClient{BaseURL: srv.URL + httpAddr.Path, Raw: true, VerifyChecksum: true}
+	res := &pb.Response{}
+	if err := client.Get(&pb.Request{Group: "gzip_pass_test", Key: "k"}, res); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if string(res.GetValue()) != large {
+		t.Fatal("client should receive the original value")
+	}
+}
+
+func TestHandler_ChecksumOnRequest(t *testing.T) {
+	group.NewGroup("checksum_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+	httpAddr := NewHttpAddr("http://localhost:8001")
+
+	for _, accept := range []string{"", httpclient.RawContentType} {
+		req := httptest.NewRequest(http.MethodGet, "/_geecache/checksum_test/k", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		httpAddr.ServeHTTP(w, req)
+		if w.Header().Get(httpclient.ChecksumHeader) != "" {
+			t.Fatalf("Accept %q: checksum should only be sent on request", accept)
+		}
+
+		req.Header.Set(httpclient.WantChecksumHeader, "1")
+		w = httptest.NewRecorder()
+		httpAddr.ServeHTTP(w, req)
+		if got := w.Header().Get(httpclient.ChecksumHeader); got != httpclient.Checksum([]byte("value-k")) {
+			t.Fatalf("Accept %q: expected checksum of the value, got %q", accept, got)
+		}
+	}
+}
+
+// ---------- 多基础路径测试 ----------
+
+func TestHandler_MultiplePaths(t *testing.T) {
+	group.NewGroup("path_a_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("a-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	cacheA := httpAddr.AddPath("/cacheA/")
+	cacheB := httpAddr.AddPath("/cacheB/")
+	if httpAddr.AddPath("/cacheA/") != cacheA {
+		t.Fatal("AddPath should return the existing HttpAddr for a registered path")
+	}
+
+	cacheA.Set("http://localhost:8001", "http://localhost:8002")
+	cacheB.Set("http://localhost:8001", "http://localhost:8003")
+	if _, ok := cacheA.HttpClients["http://localhost:8003"]; ok {
+		t.Fatal("paths should not share client sets")
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", httpAddr.Handler())
+
+	for _, path := range []string{"/cacheA/path_a_test/k", "/cacheB/path_a_test/k", "/_geecache/path_a_test/k"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestNewHttpAddrWithPath(t *testing.T) {
+	group.NewGroup("with_path_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("v-" + key), nil
+		}))
+
+	if got := NewHttpAddrWithPath("", "cacheB").Path; got != "/cacheB/" {
+		t.Fatalf("path should be normalized, got %q", got)
+	}
+
+	// 两个集群挂在同一个路由器的不同路径下
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	a := NewHttpAddrWithPath(srv.URL, "/clusterA/")
+	b := NewHttpAddrWithPath(srv.URL, "/clusterB/")
+	mux.Handle(a.Path, a.Handler())
+	mux.Handle(b.Path, b.Handler())
+
+	a.Set(srv.URL)
+	if client := a.HttpClients[srv.URL]; client.BaseURL != srv.URL+"/clusterA/" {
+		t.Fatalf("client should use the instance path, got %s", client.BaseURL)
+	}
+	for _, addr := range []*HttpAddr{a, b} {
+		client := &httpclient.HttpClient{BaseURL: srv.URL + addr.Path}
+		out := &pb.Response{}
+		if err := client.Get(&pb.Request{Group: "with_path_test", Key: "k"}, out); err != nil || string(out.GetValue()) != "v-k" {
+			t.Fatalf("%s: unexpected result %q, %v", addr.Path, out.GetValue(), err)
+		}
+	}
+}
+
+// ---------- 批量写入测试 ----------
+
+func TestHandler_BatchSet(t *testing.T) {
+	g := group.NewGroup("batch_set_test", 2<<10, nil, group.WithoutLoader())
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	server := httptest.NewServer(httpAddr.Handler())
+	defer server.Close()
+
+	client := &httpclient.HttpClient{BaseURL: server.URL + "/_geecache/"}
+	stored, err := client.SetBatch("batch_set_test", map[string][]byte{
+		"Tom":  []byte("630"),
+		"Jack": []byte("589"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored != 2 {
+		t.Fatalf("expected 2 stored entries, got %d", stored)
+	}
+	if v, err := g.Get("Jack"); err != nil || v.String() != "589" {
+		t.Fatalf("expected '589', got '%s' (%v)", v.String(), err)
+	}
+
+	if _, err := client.SetBatch("missing_group", nil); err == nil {
+		t.Fatal("expected error for missing group")
+	}
+}
+
+func TestHandler_MaxBodyBytes(t *testing.T) {
+	g := group.NewGroup("max_body_test", 2<<10, nil, group.WithoutLoader())
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.MaxBodyBytes = 64
+	server := httptest.NewServer(httpAddr.Handler())
+	defer server.Close()
+
+	client := &httpclient.HttpClient{BaseURL: server.URL + "/_geecache/"}
+	large := bytes.Repeat([]byte("x"), 128)
+	var e *httpclient.Error
+	err := client.Set(&pb.Request{Group: "max_body_test", Key: "big"}, large)
+	if !errors.As(err, &e) || e.Status != http.StatusRequestEntityTooLarge || e.Code != pb.ErrorCode_PAYLOAD_TOO_LARGE {
+		t.Fatalf("expected 413 for an oversized PUT, got %v", err)
+	}
+	_, err = client.SetBatch("max_body_test", map[string][]byte{"big": large})
+	if !errors.As(err, &e) || e.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413 for an oversized batch, got %v", err)
+	}
+	if _, ok := g.Stat("big"); ok {
+		t.Fatal("oversized value should not be stored")
+	}
+
+	// 上限以内的写入不受影响
+	if err := client.Set(&pb.Request{Group: "max_body_test", Key: "small"}, []byte("630")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// ---------- 删除测试 ----------
+
+type fixedPicker struct {
+	peer pickpeer.PeerGetter
+}
+
+func (p fixedPicker) PickPeer(key string) (pickpeer.PeerGetter, bool) { return p.peer, true }
+
+func TestGroup_RemoveThroughPeer(t *testing.T) {
+	g := group.NewGroup("remove_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	var deletes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			deletes = append(deletes, r.URL.Path)
+		}
+		httpAddr.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	g.RegisterPeers(fixedPicker{&httpclient.HttpClient{BaseURL: srv.URL + httpAddr.Path}})
+
+	g.Set("Tom", []byte("630"))
+	if err := g.Remove("Tom"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok := g.Stat("Tom"); ok {
+		t.Fatal("local entry should be removed")
+	}
+	if len(deletes) != 1 || deletes[0] != "/_geecache/remove_test/Tom" {
+		t.Fatalf("owning peer should receive one DELETE, got %v", deletes)
+	}
+}
+
+// ---------- 限流测试 ----------
+
+func TestHandler_Rejected(t *testing.T) {
+	group.NewGroup("rejected_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return nil, fmt.Errorf("limiter: %w", &group.RejectedError{RetryAfter: 1500 * time.Millisecond})
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	req, _ := http.NewRequest("GET", "/_geecache/rejected_test/Tom", nil)
+	w := httptest.NewRecorder()
+	httpAddr.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Fatalf("expected Retry-After 2, got %q", got)
+	}
+}
+
+// ---------- 流式批量读取测试 ----------
+
+func TestHandler_BatchGet(t *testing.T) {
+	release := make(chan struct{})
+	group.NewGroup("batch_get_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			switch key {
+			case "slow":
+				// 直到客户端收到 fast 才返回，验证结果是逐个推送而不是整体缓冲
+				<-release
+			case "bad":
+				return nil, fmt.Errorf("%s not exist", key)
+			}
+			return []byte("value-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	srv := httptest.NewServer(httpAddr.Handler())
+	defer srv.Close()
+
+	client := &httpclient.HttpClient{BaseURL: srv.URL + httpAddr.Path}
+	got := make(map[string]string)
+	var order []string
+	err := client.GetBatch("batch_get_test", []string{"slow", "fast", "bad"}, func(key string, value []byte, err error) {
+		order = append(order, key)
+		if err != nil {
+			got[key] = "error"
+		} else {
+			got[key] = string(value)
+		}
+		if key == "fast" {
+			close(release)
+		}
+	})
+	if err != nil {
+		t.Fatalf("GetBatch failed: %v", err)
+	}
+
+	want := map[string]string{"slow": "value-slow", "fast": "value-fast", "bad": "error"}
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("%s: expected %q, got %q", key, v, got[key])
+		}
+	}
+	if order[len(order)-1] != "slow" {
+		t.Fatalf("slow key should resolve last, got order %v", order)
+	}
+}
+
+func TestHandler_BatchGetConcurrency(t *testing.T) {
+	var active, peak, loads atomic.Int32
+	group.NewGroup("batch_get_limit_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			loads.Add(1)
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return []byte("value-" + key), nil
+		}))
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	httpAddr.BatchConcurrency = 2
+	srv := httptest.NewServer(httpAddr.Handler())
+	defer srv.Close()
+
+	var keys []string
+	for i := 0; i < 10; i++ {
+		keys = append(keys, fmt.Sprintf("k%d", i))
+	}
+	client := &httpclient.HttpClient{BaseURL: srv.URL + httpAddr.Path}
+	n := 0
+	err := client.GetBatch("batch_get_limit_test", keys, func(key string, value []byte, err error) {
+		n++
+	})
+	if err != nil || n != len(keys) {
+		t.Fatalf("expected %d results, got %d and %v", len(keys), n, err)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("at most 2 keys should load at once, got %d", peak.Load())
+	}
+
+	// 客户端断开后不再启动新的加载
+	for i := range keys {
+		keys[i] = "gone-" + keys[i]
+	}
+	loads.Store(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+		srv.URL+httpAddr.Path+"batch_get_limit_test?"+url.Values{"key": keys}.Encode(), nil)
+	go func() {
+		time.Sleep(15 * time.Millisecond)
+		cancel()
+	}()
+	if res, err := http.DefaultClient.Do(req); err == nil {
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := loads.Load(); got >= int32(len(keys)) {
+		t.Fatalf("loads should stop after the client disconnects, got %d", got)
+	}
+}
+
+// ---------- group/key 编码往返模糊测试 ----------
+
+func FuzzKeyRoundTrip(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"scores", "Tom"},
+		{"a/b", "c/d"},
+		{"g", "/"},
+		{"g", ""},
+		{"g", ".."},
+		{"空格 group", "键 with space+plus"},
+		{"g", "%2F%zz?x=1#frag"},
+		{"g", "\xff\x00"},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+
+	httpAddr := NewHttpAddr("http://localhost:8001")
+	srv := httptest.NewServer(httpAddr.Handler())
+	defer srv.Close()
+	client := &httpclient.HttpClient{BaseURL: srv.URL + httpAddr.Path}
+
+	f.Fuzz(func(t *testing.T, groupName, key string) {
+		if groupName == "" {
+			// 空 group 名无法注册为有意义的 Group
+			t.Skip()
+		}
+		group.GetOrCreateGroup("fuzz:"+groupName, 2<<10, callbackfunc.CallbackFunc(
+			func(k string) ([]byte, error) {
+				return []byte("fuzz:" + groupName + "\x00" + k), nil
+			}))
+
+		res := &pb.Response{}
+		if err := client.Get(&pb.Request{Group: "fuzz:" + groupName, Key: key}, res); err != nil {
+			t.Fatalf("Get(%q, %q) failed: %v", groupName, key, err)
+		}
+		if want := "fuzz:" + groupName + "\x00" + key; string(res.GetValue()) != want {
+			t.Fatalf("server parsed %q, want %q", res.GetValue(), want)
+		}
+	})
+}
+
+// ---------- 优雅关闭测试 ----------
+
+func TestServer_Shutdown(t *testing.T) {
+	started := make(chan struct{}, 1)
+	group.NewGroup("server_shutdown_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			started <- struct{}{}
+			time.Sleep(100 * time.Millisecond)
+			return []byte("value-" + key), nil
+		}))
+
+	// 取一个空闲端口
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := ln.Addr().String()
+	ln.Close()
+
+	httpAddr := NewHttpAddr("http://" + listen)
+	httpAddr.Set(httpAddr.Host)
+	srv := NewServer(httpAddr)
+	done := make(chan error, 1)
+	go func() { done <- srv.Start(listen) }()
+
+	url := "http://" + listen + "/_geecache/server_shutdown_test/k"
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", listen); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 关闭时进行中的请求应正常完成
+	inflight := make(chan error, 1)
+	go func() {
+		res, err := http.Get(url)
+		if err == nil {
+			if res.StatusCode != http.StatusOK {
+				err = fmt.Errorf("status %d", res.StatusCode)
+			}
+			res.Body.Close()
+		}
+		inflight <- err
+	}()
+	<-started
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := <-inflight; err != nil {
+		t.Fatalf("in-flight request should be drained, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Start should return nil after Shutdown, got %v", err)
+	}
+
+	// 关闭后不再接受新请求与节点列表变更
+	if _, err := http.Get(url); err == nil {
+		t.Fatal("server should not accept requests after Shutdown")
+	}
+	httpAddr.Set("http://other:8001")
+	if got := httpAddr.Candidates("k"); len(got) != 1 || got[0] != httpAddr.Host {
+		t.Fatalf("peer updates should be ignored during shutdown, got %v", got)
+	}
+}
+
+// writeCert 在 dir 中生成一张自签名证书及其私钥，返回文件路径，证书可同时用作 CA、服务端与客户端证书
+func writeCert(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestServer_StartTLS(t *testing.T) {
+	group.NewGroup("server_tls_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "node")
+	cfg, err := httpclient.LoadMutualTLS(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	listen := ln.Addr().String()
+	ln.Close()
+	srv := NewServer(NewHttpAddr("https://" + listen))
+	srv.TLSConfig = cfg
+	done := make(chan error, 1)
+	go func() { done <- srv.StartTLS(listen, certFile, keyFile) }()
+	defer func() {
+		srv.Shutdown(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("StartTLS should return nil after Shutdown, got %v", err)
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if conn, err := net.Dial("tcp", listen); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 双向认证：出示集群证书的节点可以访问
+	peer := "https://" + listen
+	httpAddr := NewHttpAddr("http://self")
+	httpAddr.TLSConfig = cfg
+	httpAddr.Set(peer)
+	req := &pb.Request{Group: "server_tls_test", Key: "k"}
+	out := &pb.Response{}
+	if err := httpAddr.HttpClients[peer].Get(req, out); err != nil || string(out.GetValue()) != "value-k" {
+		t.Fatalf("expected value over mutual TLS, got %q, %v", out.GetValue(), err)
+	}
+
+	// 不出示客户端证书的请求被拒绝
+	httpAddr.ReconfigureClients(ClientConfig{TLSConfig: &tls.Config{RootCAs: cfg.RootCAs}})
+	if err := httpAddr.HttpClients[peer].Get(req, &pb.Response{}); err == nil {
+		t.Fatal("clients without a certificate should be rejected")
+	}
+
+	// 固定其他证书时拒绝该节点
+	otherCert, _ := writeCert(t, dir, "other")
+	other, _ := tls.LoadX509KeyPair(otherCert, filepath.Join(dir, "other.key"))
+	leaf, _ := x509.ParseCertificate(other.Certificate[0])
+	pinned := cfg.Clone()
+	pinned.VerifyPeerCertificate = httpclient.PinCertificates(httpclient.CertificateFingerprint(leaf))
+	httpAddr.ReconfigureClients(ClientConfig{TLSConfig: pinned})
+	if err := httpAddr.HttpClients[peer].Get(req, &pb.Response{}); err == nil {
+		t.Fatal("peers with an unpinned certificate should be rejected")
+	}
+}
+
+func TestHttpAddr_HealthCheck(t *testing.T) {
+	var down atomic.Bool
+	peer := NewHttpAddr("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		peer.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	httpAddr := NewHttpAddr("http://self")
+	httpAddr.SetWeighted(map[string]int{httpAddr.Host: 1, srv.URL: 2})
+	digest := httpAddr.RingDigest()
+	var key string
+	for i := 0; ; i++ {
+		key = fmt.Sprintf("key-%d", i)
+		if httpAddr.Candidates(key)[0] == srv.URL {
+			break
+		}
+	}
+
+	// 节点宕机：连续失败后被移出哈希环
+	down.Store(true)
+	httpAddr.EnableHealthCheck(10 * time.Millisecond)
+	defer httpAddr.EnableHealthCheck(0)
+	waitFor(t, func() bool { return httpAddr.Candidates(key)[0] == httpAddr.Host })
+	if _, ok := httpAddr.PickPeer(key); ok {
+		t.Fatal("removed peer should not be picked")
+	}
+
+	// 节点恢复：按原权重加回
+	down.Store(false)
+	waitFor(t, func() bool { return httpAddr.Candidates(key)[0] == srv.URL })
+	if got := httpAddr.RingDigest(); got != digest {
+		t.Fatal("recovered peer should be added back with its original weight")
+	}
+
+	// 下线期间健康检查端点返回 503
+	peer.closing.Store(true)
+	if err := (&httpclient.HttpClient{BaseURL: srv.URL + "/_geecache/"}).Ping(context.Background()); err == nil {
+		t.Fatal("Ping should fail while the peer is shutting down")
+	}
+}
+
+// waitFor 轮询 cond 直到其为 true，超时则测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,73 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Server 管理 HttpAddr 对外服务的生命周期：Start 监听并处理请求，
+// Shutdown 停止接收新连接、等待进行中的请求处理完毕，再执行 HttpAddr.Shutdown 的下线交接。
+type Server struct {
+	addr *HttpAddr
+
+	// TLSConfig 是 StartTLS 使用的 TLS 配置，如要求并校验客户端证书以实现节点间双向认证，
+	// 见 httpclient.LoadMutualTLS；需在 StartTLS 之前设置，StartTLS 使用它的副本
+	TLSConfig *tls.Config
+
+	mu  sync.Mutex
+	srv *http.Server
+}
+
+func NewServer(addr *HttpAddr) *Server {
+	return &Server{addr: addr}
+}
+
+// Start 在 listen（如 ":8001"）上处理 HttpAddr 的请求，阻塞直到出错或 Shutdown 被调用。
+// 因 Shutdown 返回时结果为 nil。
+func (s *Server) Start(listen string) error {
+	return s.serve(listen, (*http.Server).ListenAndServe)
+}
+
+// StartTLS 与 Start 相同，但以 HTTPS 提供服务。certFile 与 keyFile 是本节点的证书与私钥，
+// TLSConfig 中已提供 Certificates 时可以为空。其他节点应以 https:// 地址访问本节点。
+func (s *Server) StartTLS(listen, certFile, keyFile string) error {
+	return s.serve(listen, func(srv *http.Server) error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+func (s *Server) serve(listen string, listenAndServe func(*http.Server) error) error {
+	s.mu.Lock()
+	if s.srv != nil {
+		s.mu.Unlock()
+		return errors.New("httpserver: server already started")
+	}
+	s.srv = &http.Server{Addr: listen, Handler: s.addr.Handler(), TLSConfig: s.TLSConfig.Clone()}
+	srv := s.srv
+	s.mu.Unlock()
+
+	if err := listenAndServe(srv); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 优雅地关闭服务：先冻结节点列表，之后的 Set、SetWeighted 与 WatchChan 更新都会被忽略；
+// 再停止接收新请求并等待进行中的请求完成；最后执行 HttpAddr.Shutdown。
+// ctx 结束时不再等待，返回 ctx.Err()。
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.addr.closing.Store(true)
+
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	return errors.Join(err, s.addr.Shutdown(ctx))
+}
@@ -0,0 +1,242 @@
+// Package lfu 实现按访问频率淘汰的缓存，接口与 lru 相同，可作为 cache.Cache 的淘汰策略。
+// 容量不足时淘汰访问次数最少的项，次数相同时淘汰最久未访问的项；
+// 一次性扫描的 key 访问次数低，不会把频繁访问的热点 key 挤出缓存。
+package lfu
+
+import (
+	"container/heap"
+	"fmt"
+	lru "geecache/LRU"
+	"sort"
+)
+
+type Cache struct {
+	maxBytes int64
+	nbytes   int64
+	items    map[string]*entry
+	heap     entryHeap
+	pinned   map[string]struct{}
+	clock    int64 // 访问序号，用于频率相同时按最近访问排序
+
+	OnEvicted func(key string, value lru.Value, reason lru.EvictReason)
+}
+
+type entry struct {
+	key      string
+	value    lru.Value
+	priority lru.Priority
+	freq     int64
+	last     int64 // 最近一次访问的序号
+	index    int   // 在堆中的位置
+}
+
+// entryHeap 是按淘汰顺序排列的最小堆：优先级低、访问次数少、最久未访问的项在堆顶
+type entryHeap []*entry
+
+// evictsBefore 判断 a 是否应先于 b 被淘汰
+func evictsBefore(a, b *entry) bool {
+	if a.priority != b.priority {
+		return a.priority < b.priority
+	}
+	if a.freq != b.freq {
+		return a.freq < b.freq
+	}
+	return a.last < b.last
+}
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return evictsBefore(h[i], h[j]) }
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+func New(maxBytes int64, onEvicted func(key string, value lru.Value, reason lru.EvictReason)) *Cache {
+	return &Cache{
+		maxBytes:  maxBytes,
+		items:     make(map[string]*entry),
+		OnEvicted: onEvicted,
+	}
+}
+
+// touch 记录一次访问
+func (c *Cache) touch(e *entry) {
+	c.clock++
+	e.freq++
+	e.last = c.clock
+	heap.Fix(&c.heap, e.index)
+}
+
+func (c *Cache) Get(key string) (lru.Value, bool) {
+	if e, ok := c.items[key]; ok {
+		c.touch(e)
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Peek 返回 key 对应的值，不计入访问次数
+func (c *Cache) Peek(key string) (lru.Value, bool) {
+	if e, ok := c.items[key]; ok {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// Range 按保留价值从高到低遍历缓存项，即与淘汰顺序相反，fn 返回 false 时停止遍历
+func (c *Cache) Range(fn func(key string, value lru.Value) bool) {
+	entries := append([]*entry(nil), c.heap...)
+	sort.Slice(entries, func(i, j int) bool { return evictsBefore(entries[j], entries[i]) })
+	for _, e := range entries {
+		if !fn(e.key, e.value) {
+			return
+		}
+	}
+}
+
+// Add 写入缓存项并计一次访问，新项使用 lru.PriorityNormal，已存在的项保留原有优先级
+func (c *Cache) Add(key string, value lru.Value) {
+	priority := lru.PriorityNormal
+	if e, ok := c.items[key]; ok {
+		priority = e.priority
+	}
+	c.AddWithPriority(key, value, priority)
+}
+
+// AddWithPriority 以指定的淘汰优先级写入缓存项，低优先级的项总是先于高优先级的项被淘汰
+func (c *Cache) AddWithPriority(key string, value lru.Value, priority lru.Priority) {
+	if e, ok := c.items[key]; ok {
+		c.nbytes += int64(value.Len()) - int64(e.value.Len())
+		e.value = value
+		e.priority = priority
+		c.touch(e)
+	} else {
+		c.clock++
+		e := &entry{key: key, value: value, priority: priority, freq: 1, last: c.clock}
+		heap.Push(&c.heap, e)
+		c.items[key] = e
+		c.nbytes += int64(len(key)) + int64(value.Len())
+	}
+	c.shrink()
+}
+
+// RemoveOldest 淘汰一个最少使用且未被固定的项
+func (c *Cache) RemoveOldest() {
+	c.evict()
+}
+
+// evict 淘汰堆顶第一个未被固定的项，没有可淘汰的项时返回 false
+func (c *Cache) evict() bool {
+	var skipped []*entry
+	defer func() {
+		for _, e := range skipped {
+			heap.Push(&c.heap, e)
+		}
+	}()
+	for c.heap.Len() > 0 {
+		e := heap.Pop(&c.heap).(*entry)
+		if _, ok := c.pinned[e.key]; ok {
+			skipped = append(skipped, e)
+			continue
+		}
+		c.remove(e, lru.ReasonCapacity)
+		return true
+	}
+	return false
+}
+
+// shrink 淘汰缓存项直到满足容量上限或没有可淘汰的项
+func (c *Cache) shrink() {
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+		if !c.evict() {
+			return
+		}
+	}
+}
+
+// remove 删除已从堆中取出的 e
+func (c *Cache) remove(e *entry, reason lru.EvictReason) {
+	delete(c.items, e.key)
+	c.nbytes -= int64(len(e.key)) + int64(e.value.Len())
+	if c.OnEvicted != nil {
+		c.OnEvicted(e.key, e.value, reason)
+	}
+}
+
+// Remove 删除 key 对应的缓存项（包括被固定的），返回 key 是否存在
+func (c *Cache) Remove(key string) bool {
+	return c.RemoveWithReason(key, lru.ReasonDeleted)
+}
+
+// RemoveWithReason 与 Remove 相同，但以 reason 触发 OnEvicted
+func (c *Cache) RemoveWithReason(key string, reason lru.EvictReason) bool {
+	e, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	delete(c.pinned, key)
+	heap.Remove(&c.heap, e.index)
+	c.remove(e, reason)
+	return true
+}
+
+// Clear 删除全部缓存项，每一项都会以 ReasonCleared 触发 OnEvicted
+func (c *Cache) Clear() {
+	for c.heap.Len() > 0 {
+		c.remove(heap.Pop(&c.heap).(*entry), lru.ReasonCleared)
+	}
+	c.pinned = nil
+}
+
+// Pin 固定已缓存的 key，使其不会被容量淘汰。
+// 若固定后被固定条目的总大小超过容量上限则返回错误，避免淘汰无法进行。
+func (c *Cache) Pin(key string) error {
+	e, ok := c.items[key]
+	if !ok {
+		return fmt.Errorf("lfu: pin %q: key not cached", key)
+	}
+	if _, ok := c.pinned[key]; ok {
+		return nil
+	}
+	var pinned int64
+	for k := range c.pinned {
+		pinned += int64(len(k)) + int64(c.items[k].value.Len())
+	}
+	size := int64(len(e.key)) + int64(e.value.Len())
+	if c.maxBytes != 0 && pinned+size > c.maxBytes {
+		return fmt.Errorf("lfu: pin %q: pinned entries would exceed capacity %d bytes", key, c.maxBytes)
+	}
+	if c.pinned == nil {
+		c.pinned = make(map[string]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	return nil
+}
+
+// Unpin 取消固定 key，之后它会重新参与淘汰
+func (c *Cache) Unpin(key string) {
+	delete(c.pinned, key)
+	c.shrink()
+}
+
+func (c *Cache) Len() int {
+	return len(c.items)
+}
+
+// Bytes 返回当前所有缓存项的 key 与 value 总字节数
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
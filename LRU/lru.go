@@ -2,6 +2,8 @@ package lru
 
 import (
 	"container/list"
+	"fmt"
+	"sort"
 )
 
 
@@ -10,13 +12,50 @@ type Cache struct {
 	nbytes   int64
 	ll       *list.List
 	cache    map[string]*list.Element
+	pinned   map[string]struct{}
+	counts   map[Priority]int // 各优先级的缓存项数
 
-	OnEvicted func(key string) ([]byte ,error)
+	OnEvicted func(key string, value Value, reason EvictReason)
 }
 
+// EvictReason 表示缓存项离开缓存的原因
+type EvictReason int
+
+const (
+	ReasonCapacity EvictReason = iota // 超出容量被淘汰
+	ReasonExpired                     // 过期
+	ReasonDeleted                     // 被显式删除
+	ReasonCleared                     // 缓存被清空
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonCleared:
+		return "cleared"
+	}
+	return "unknown"
+}
+
+// Priority 是缓存项的淘汰优先级。容量淘汰总是先淘汰最低优先级中最久未使用的项，
+// 低优先级的项全部淘汰后才会淘汰更高优先级的项
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0 // Add 写入的新项的默认优先级
+	PriorityHigh   Priority = 1
+)
+
 type entry struct {
-	key   string
-	value Value
+	key      string
+	value    Value
+	priority Priority
 }
 
 
@@ -24,11 +63,12 @@ type Value interface {
 	Len() int
 }
 
-func New(maxBytes int64, onEvicted func(key string) ([]byte ,error)) *Cache {
+func New(maxBytes int64, onEvicted func(key string, value Value, reason EvictReason)) *Cache {
 	return &Cache{
 		maxBytes:  maxBytes,
 		ll:        list.New(),
 		cache : make(map[string]*list.Element),
+		counts:    make(map[Priority]int),
 		OnEvicted: onEvicted,
 	}
 }
@@ -43,35 +83,197 @@ func (c *Cache) Get(key string) (Value,bool){
 	return nil,false
 }
 
+// Peek 返回 key 对应的值，但不改变其在链表中的位置
+func (c *Cache) Peek(key string) (Value, bool) {
+	if element, ok := c.cache[key]; ok {
+		return element.Value.(*entry).value, true
+	}
+	return nil, false
+}
+
+// Range 从最近使用到最久未使用依次遍历缓存项，fn 返回 false 时停止遍历
+func (c *Cache) Range(fn func(key string, value Value) bool) {
+	for element := c.ll.Front(); element != nil; element = element.Next() {
+		kv := element.Value.(*entry)
+		if !fn(kv.key, kv.value) {
+			return
+		}
+	}
+}
+
+// Keys 返回全部 key，从最近使用到最久未使用排列
+func (c *Cache) Keys() []string {
+	keys := make([]string, 0, len(c.cache))
+	for element := c.ll.Front(); element != nil; element = element.Next() {
+		keys = append(keys, element.Value.(*entry).key)
+	}
+	return keys
+}
+
 func (c *Cache) Delete()  {
-	element := c.ll.Back()
-	if element != nil {
-		c.ll.Remove(element)
+	c.evict()
+}
+
+// RemoveOldest 淘汰一个最久未使用且未被固定的缓存项，与 Delete 相同
+func (c *Cache) RemoveOldest() {
+	c.evict()
+}
+
+// evict 淘汰最低优先级中最久未使用且未被固定的缓存项，没有可淘汰的项时返回 false
+func (c *Cache) evict() bool {
+	// 只有一种优先级时（默认情况）就是普通的 LRU
+	if len(c.counts) <= 1 {
+		return c.evictPriority(nil)
+	}
+	priorities := make([]Priority, 0, len(c.counts))
+	for p := range c.counts {
+		priorities = append(priorities, p)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] < priorities[j] })
+	for _, p := range priorities {
+		if c.evictPriority(&p) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictPriority 从链表尾部起淘汰第一个未被固定、优先级为 *p 的缓存项，p 为 nil 时不限优先级
+func (c *Cache) evictPriority(p *Priority) bool {
+	for element := c.ll.Back(); element != nil; element = element.Prev() {
 		kv := element.Value.(*entry)
-		delete(c.cache, kv.key)
-		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
-		if c.OnEvicted != nil {
-			c.OnEvicted(kv.key)
+		if p != nil && kv.priority != *p {
+			continue
+		}
+		if _, ok := c.pinned[kv.key]; ok {
+			continue
+		}
+		c.removeElement(element, ReasonCapacity)
+		return true
+	}
+	return false
+}
+
+// Remove 删除 key 对应的缓存项（包括被固定的），返回 key 是否存在
+func (c *Cache) Remove(key string) bool {
+	return c.RemoveWithReason(key, ReasonDeleted)
+}
+
+// RemoveWithReason 与 Remove 相同，但以 reason 触发 OnEvicted
+func (c *Cache) RemoveWithReason(key string, reason EvictReason) bool {
+	element, ok := c.cache[key]
+	if !ok {
+		return false
+	}
+	delete(c.pinned, key)
+	c.removeElement(element, reason)
+	return true
+}
+
+// Clear 删除全部缓存项，每一项都会以 ReasonCleared 触发 OnEvicted
+func (c *Cache) Clear() {
+	for element := c.ll.Back(); element != nil; element = c.ll.Back() {
+		c.removeElement(element, ReasonCleared)
+	}
+	c.pinned = nil
+}
+
+func (c *Cache) removeElement(element *list.Element, reason EvictReason) {
+	c.ll.Remove(element)
+	kv := element.Value.(*entry)
+	delete(c.cache, kv.key)
+	c.uncount(kv.priority)
+	c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value, reason)
+	}
+}
+
+// Pin 固定已缓存的 key，使其不会被容量淘汰。
+// 若固定后被固定条目的总大小超过容量上限则返回错误，避免淘汰无法进行。
+func (c *Cache) Pin(key string) error {
+	element, ok := c.cache[key]
+	if !ok {
+		return fmt.Errorf("lru: pin %q: key not cached", key)
+	}
+	if _, ok := c.pinned[key]; ok {
+		return nil
+	}
+	kv := element.Value.(*entry)
+	size := int64(len(kv.key)) + int64(kv.value.Len())
+	if c.maxBytes != 0 && c.pinnedBytes()+size > c.maxBytes {
+		return fmt.Errorf("lru: pin %q: pinned entries would exceed capacity %d bytes", key, c.maxBytes)
+	}
+	if c.pinned == nil {
+		c.pinned = make(map[string]struct{})
+	}
+	c.pinned[key] = struct{}{}
+	return nil
+}
+
+// Unpin 取消固定 key，之后它会重新参与淘汰
+func (c *Cache) Unpin(key string) {
+	delete(c.pinned, key)
+	c.shrink()
+}
+
+func (c *Cache) pinnedBytes() int64 {
+	var n int64
+	for key := range c.pinned {
+		if element, ok := c.cache[key]; ok {
+			n += int64(len(key)) + int64(element.Value.(*entry).value.Len())
 		}
 	}
+	return n
 }
 
+// shrink 淘汰缓存项直到满足容量上限或没有可淘汰的项
+func (c *Cache) shrink() {
+	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
+		if !c.evict() {
+			return
+		}
+	}
+}
+
+// Add 写入缓存项，新项使用 PriorityNormal，已存在的项保留原有优先级
 func (c *Cache) Add(key string, value Value)  {
+	priority := PriorityNormal
+	if element, ok := c.cache[key]; ok {
+		priority = element.Value.(*entry).priority
+	}
+	c.AddWithPriority(key, value, priority)
+}
+
+// AddWithPriority 以指定的淘汰优先级写入缓存项，见 Priority
+func (c *Cache) AddWithPriority(key string, value Value, priority Priority) {
 	if element,ok := c.cache[key];ok{
 		c.ll.MoveToFront(element)
 		kv := element.Value.(*entry)
 		c.nbytes += int64(value.Len()) - int64(kv.value.Len())
 		kv.value = value
+		c.uncount(kv.priority)
+		kv.priority = priority
 	}else{
-		element := c.ll.PushFront(&entry{key,value})
+		element := c.ll.PushFront(&entry{key, value, priority})
 		c.cache[key] = element
 		c.nbytes += int64(len(key)) + int64(value.Len())
 	}
-	for c.maxBytes != 0 && c.nbytes > c.maxBytes {
-		c.Delete()
+	c.counts[priority]++
+	c.shrink()
+}
+
+func (c *Cache) uncount(priority Priority) {
+	if c.counts[priority]--; c.counts[priority] == 0 {
+		delete(c.counts, priority)
 	}
 }
 
 func (c *Cache) Len() int {
 	return c.ll.Len()
-}
\ No newline at end of file
+}
+
+// Bytes 返回当前所有缓存项的 key 与 value 总字节数
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
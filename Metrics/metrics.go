@@ -0,0 +1,71 @@
+// Package metrics 以 Prometheus 文本格式导出所有已注册 Group 的访问计数，
+// 每个指标以 group 标签区分，见 group.Stats。
+package metrics
+
+import (
+	"fmt"
+	group "geecache/Group"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ContentType 是 Prometheus 文本格式的媒体类型
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metric 描述一个导出的计数器
+type metric struct {
+	name  string
+	help  string
+	value func(s group.Stats) int64
+}
+
+var metrics = []metric{
+	{"geecache_gets_total", "Total number of Get calls.", func(s group.Stats) int64 { return s.Gets }},
+	{"geecache_local_hits_total", "Gets served from the local cache.", func(s group.Stats) int64 { return s.LocalHits }},
+	{"geecache_peer_hits_total", "Gets served by a remote peer.", func(s group.Stats) int64 { return s.PeerHits }},
+	{"geecache_loads_total", "Callback loads from the data source.", func(s group.Stats) int64 { return s.Loads }},
+	{"geecache_load_errors_total", "Callback loads that returned an error.", func(s group.Stats) int64 { return s.LoadErrors }},
+	{"geecache_coalesced_total", "Gets that shared an in-flight load.", func(s group.Stats) int64 { return s.Coalesced }},
+}
+
+// Write 将当前所有已注册 Group 的指标写入 w，新建的 Group 在下次调用时自动出现
+func Write(w io.Writer) error {
+	names := group.ListGroups()
+	sort.Strings(names)
+	stats := make([]group.Stats, 0, len(names))
+	for i := 0; i < len(names); i++ {
+		g := group.GetGroup(names[i])
+		if g == nil {
+			// 导出期间被删除
+			names = append(names[:i], names[i+1:]...)
+			i--
+			continue
+		}
+		stats = append(stats, g.Stats())
+	}
+
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", m.name, m.help, m.name)
+		for i, name := range names {
+			fmt.Fprintf(&b, "%s{group=\"%s\"} %d\n", m.name, escapeLabel(name), m.value(stats[i]))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabel 按 Prometheus 文本格式转义标签值
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// Handler 返回导出指标的 http.Handler，可挂在 /metrics 上供 Prometheus 抓取
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		Write(w)
+	})
+}
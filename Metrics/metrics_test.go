@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"fmt"
+	callbackfunc "geecache/CallbackFunc"
+	group "geecache/Group"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	g := group.NewGroup("metrics_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, fmt.Errorf("%s not exist", key)
+			}
+			return []byte("v"), nil
+		}))
+	g.Get("k")
+	g.Get("k")
+	g.Get("missing")
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE geecache_gets_total counter",
+		`geecache_gets_total{group="metrics_test"} 3`,
+		`geecache_local_hits_total{group="metrics_test"} 1`,
+		`geecache_loads_total{group="metrics_test"} 2`,
+		`geecache_load_errors_total{group="metrics_test"} 1`,
+		`geecache_peer_hits_total{group="metrics_test"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("unexpected escape %q", got)
+	}
+}
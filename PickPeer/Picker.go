@@ -1,6 +1,9 @@
 package pickpeer
 
-import pb "geecache/geecachepb"
+import (
+	"context"
+	pb "geecache/geecachepb"
+)
 
 type PeerPicker interface {
 	PickPeer(key string) (peer PeerGetter, ok bool)
@@ -9,3 +12,28 @@ type PeerPicker interface {
 type PeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
+
+// PeerGetterContext 是支持取消的 PeerGetter，实现时 Group.GetContext 的 ctx 会传给远程请求
+type PeerGetterContext interface {
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// ReplicaPicker 按哈希环顺序返回 key 的至多 n 个远程节点（不含自身）
+type ReplicaPicker interface {
+	PickPeers(key string, n int) (peers []PeerGetter)
+}
+
+// PeerDeleter 删除远程节点缓存中的 key
+type PeerDeleter interface {
+	Delete(in *pb.Request) error
+}
+
+// PreviousOwnerPicker 在哈希环变更后的一段时间内，返回 key 在变更前的归属节点（仍存活且不是自身时）
+type PreviousOwnerPicker interface {
+	PickPreviousOwner(key string) (peer PeerGetter, ok bool)
+}
+
+// PeerSetter 将 value 写入远程节点的缓存
+type PeerSetter interface {
+	Set(in *pb.Request, value []byte) error
+}
@@ -0,0 +1,10 @@
+package pickpeer
+
+import "errors"
+
+// ErrCacheMiss 表示没有回调函数的 Group 在本地和远程节点均未命中。
+// 定义在这里而不是 group 包中，使节点传输实现无需依赖 group 即可把远程错误映射为它
+var ErrCacheMiss = errors.New("geecache: cache miss")
+
+// ErrNotFound 表示数据源中不存在该 key，由 group.ErrNotFound 导出
+var ErrNotFound = errors.New("geecache: key not found")
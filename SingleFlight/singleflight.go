@@ -2,42 +2,178 @@ package singleflight
 
 import (
 	"sync"
-
-
+	"time"
 )
 
 type call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
+	wg    sync.WaitGroup
+	val   interface{}
+	err   error
+	dups  int
+	chans []waiter // DoChan 的调用方
+}
+
+// waiter 是 DoChan 的调用方，since 是跟随调用加入的时间，仅在 Timing 开启时记录
+type waiter struct {
+	ch    chan<- Result
+	since time.Time
+}
+
+// Result 是 DoChan 通过 channel 返回的结果
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool // 结果是否被多个调用方共享
 }
 
 type Group struct {
 	mu sync.Mutex       
 	m  map[string]*call
+
+	// Timing 为 true 时记录领头调用执行 fn 的耗时与跟随调用的等待时间，见 Stats
+	Timing bool
+
+	calls     int64
+	coalesced int64
+
+	leaders      int64
+	leaderTime   time.Duration
+	followers    int64
+	followerWait time.Duration
+}
+
+// Stats 是请求合并的统计
+type Stats struct {
+	Calls     int64 // Do 的总调用次数
+	Coalesced int64 // 等待并共享了其他调用结果的次数
+
+	// 以下仅在 Timing 开启后统计
+	LeaderAvg   time.Duration // 领头调用执行 fn 的平均耗时
+	FollowerAvg time.Duration // 跟随调用从加入到拿到结果的平均等待时间
+}
+
+// Stats 返回当前的统计
+func (g *Group) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s := Stats{Calls: g.calls, Coalesced: g.coalesced}
+	if g.leaders > 0 {
+		s.LeaderAvg = g.leaderTime / time.Duration(g.leaders)
+	}
+	if g.followers > 0 {
+		s.FollowerAvg = g.followerWait / time.Duration(g.followers)
+	}
+	return s
+}
+
+// ResetStats 原子地将统计清零
+func (g *Group) ResetStats() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.calls, g.coalesced = 0, 0
+	g.leaders, g.leaderTime, g.followers, g.followerWait = 0, 0, 0, 0
 }
 
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	v, err, _ := g.DoShared(key, fn)
+	return v, err
+}
+
+// DoShared 与 Do 相同，额外返回结果是否被多个调用方共享
+func (g *Group) DoShared(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
+	g.calls++
+	var start time.Time
+	if g.Timing {
+		start = time.Now()
+	}
 	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.coalesced++
 		g.mu.Unlock()
 		c.wg.Wait()
-		return c.val, c.err
+		if !start.IsZero() {
+			g.mu.Lock()
+			g.followers++
+			g.followerWait += time.Since(start)
+			g.mu.Unlock()
+		}
+		return c.val, c.err, true
 	}
 	c := new(call)
 	c.wg.Add(1)
 	g.m[key] = c
 	g.mu.Unlock()
 
-	c.val, c.err = fn()
-	c.wg.Done()
+	shared = g.doCall(c, key, fn, start)
+	return c.val, c.err, shared
+}
 
+// DoChan 与 Do 相同，但立即返回一个 channel，结果就绪后写入其中。
+// 调用方可以同时 select ctx.Done() 在取消时放弃等待；fn 仍会执行完毕，
+// 结果照常交给其他等待者。返回的 channel 带缓冲，无人接收也不会阻塞 fn 所在的 goroutine。
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	g.calls++
+	var start time.Time
+	if g.Timing {
+		start = time.Now()
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		g.coalesced++
+		c.chans = append(c.chans, waiter{ch, start})
+		g.mu.Unlock()
+		return ch
+	}
+	c := &call{chans: []waiter{{ch: ch}}}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	go g.doCall(c, key, fn, start)
+	return ch
+}
+
+// Forget 让 key 的下一次调用重新执行 fn，而不是等待正在进行的调用。
+// 已在等待的调用方仍会拿到进行中调用的结果。适用于进行中的调用已知会失败或过慢的情况，
+// 避免一次瞬时错误被放大到所有合并的调用方。
+func (g *Group) Forget(key string) {
 	g.mu.Lock()
 	delete(g.m, key)
 	g.mu.Unlock()
+}
+
+// doCall 执行领头调用并把结果交给所有等待者，返回结果是否被共享
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error), start time.Time) bool {
+	c.val, c.err = fn()
+
+	g.mu.Lock()
+	// 调用期间 key 可能已被 Forget，之后发起的新调用不能被误删
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+	shared := c.dups > 0
+	for _, w := range c.chans {
+		w.ch <- Result{Val: c.val, Err: c.err, Shared: shared}
+		if !w.since.IsZero() {
+			g.followers++
+			g.followerWait += time.Since(w.since)
+		}
+	}
+	if !start.IsZero() {
+		g.leaders++
+		g.leaderTime += time.Since(start)
+	}
+	g.mu.Unlock()
+	c.wg.Done()
 
-	return c.val, c.err
+	return shared
 }
\ No newline at end of file
@@ -21,6 +21,83 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ErrorCode 区分 Serve 返回的错误类别，客户端据此决定是否回源或重试
+type ErrorCode int32
+
+const (
+	ErrorCode_INTERNAL              ErrorCode = 0
+	ErrorCode_BAD_REQUEST           ErrorCode = 1
+	ErrorCode_GROUP_NOT_FOUND       ErrorCode = 2
+	ErrorCode_KEY_NOT_FOUND         ErrorCode = 3
+	ErrorCode_UNAVAILABLE           ErrorCode = 4
+	ErrorCode_INSUFFICIENT_STORAGE  ErrorCode = 5
+	ErrorCode_METHOD_NOT_ALLOWED    ErrorCode = 6
+	ErrorCode_CACHE_MISS            ErrorCode = 7
+	ErrorCode_RANGE_NOT_SATISFIABLE ErrorCode = 8
+	ErrorCode_UNAUTHENTICATED       ErrorCode = 9
+	ErrorCode_PERMISSION_DENIED     ErrorCode = 10
+	ErrorCode_PAYLOAD_TOO_LARGE     ErrorCode = 11
+)
+
+// Enum value maps for ErrorCode.
+var (
+	ErrorCode_name = map[int32]string{
+		0:  "INTERNAL",
+		1:  "BAD_REQUEST",
+		2:  "GROUP_NOT_FOUND",
+		3:  "KEY_NOT_FOUND",
+		4:  "UNAVAILABLE",
+		5:  "INSUFFICIENT_STORAGE",
+		6:  "METHOD_NOT_ALLOWED",
+		7:  "CACHE_MISS",
+		8:  "RANGE_NOT_SATISFIABLE",
+		9:  "UNAUTHENTICATED",
+		10: "PERMISSION_DENIED",
+		11: "PAYLOAD_TOO_LARGE",
+	}
+	ErrorCode_value = map[string]int32{
+		"INTERNAL":              0,
+		"BAD_REQUEST":           1,
+		"GROUP_NOT_FOUND":       2,
+		"KEY_NOT_FOUND":         3,
+		"UNAVAILABLE":           4,
+		"INSUFFICIENT_STORAGE":  5,
+		"METHOD_NOT_ALLOWED":    6,
+		"CACHE_MISS":            7,
+		"RANGE_NOT_SATISFIABLE": 8,
+		"UNAUTHENTICATED":       9,
+		"PERMISSION_DENIED":     10,
+		"PAYLOAD_TOO_LARGE":     11,
+	}
+)
+
+func (x ErrorCode) Enum() *ErrorCode {
+	p := new(ErrorCode)
+	*p = x
+	return p
+}
+
+func (x ErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_geecachepb_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorCode) Type() protoreflect.EnumType {
+	return &file_geecachepb_proto_enumTypes[0]
+}
+
+func (x ErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorCode.Descriptor instead.
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{0}
+}
+
 type Request struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
@@ -76,6 +153,9 @@ func (x *Request) GetKey() string {
 type Response struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,2,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Node          string                 `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	Version       int64                  `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,6 +197,280 @@ func (x *Response) GetValue() []byte {
 	return nil
 }
 
+func (x *Response) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Response) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *Response) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Entry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Entry) Reset() {
+	*x = Entry{}
+	mi := &file_geecachepb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Entry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Entry) ProtoMessage() {}
+
+func (x *Entry) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Entry.ProtoReflect.Descriptor instead.
+func (*Entry) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Entry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Entry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type BatchSetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*Entry               `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchSetRequest) Reset() {
+	*x = BatchSetRequest{}
+	mi := &file_geecachepb_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchSetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchSetRequest) ProtoMessage() {}
+
+func (x *BatchSetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchSetRequest.ProtoReflect.Descriptor instead.
+func (*BatchSetRequest) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BatchSetRequest) GetEntries() []*Entry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type BatchSetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stored        int64                  `protobuf:"varint,1,opt,name=stored,proto3" json:"stored,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchSetResponse) Reset() {
+	*x = BatchSetResponse{}
+	mi := &file_geecachepb_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchSetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchSetResponse) ProtoMessage() {}
+
+func (x *BatchSetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchSetResponse.ProtoReflect.Descriptor instead.
+func (*BatchSetResponse) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchSetResponse) GetStored() int64 {
+	if x != nil {
+		return x.Stored
+	}
+	return 0
+}
+
+type BatchGetResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetResult) Reset() {
+	*x = BatchGetResult{}
+	mi := &file_geecachepb_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetResult) ProtoMessage() {}
+
+func (x *BatchGetResult) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetResult.ProtoReflect.Descriptor instead.
+func (*BatchGetResult) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchGetResult) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *BatchGetResult) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *BatchGetResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Error 是 Serve 出错时的响应体
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          ErrorCode              `protobuf:"varint,1,opt,name=code,proto3,enum=geecachepb.ErrorCode" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_geecachepb_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_geecachepb_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_geecachepb_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Error) GetCode() ErrorCode {
+	if x != nil {
+		return x.Code
+	}
+	return ErrorCode_INTERNAL
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_geecachepb_proto protoreflect.FileDescriptor
 
 const file_geecachepb_proto_rawDesc = "" +
@@ -125,12 +479,42 @@ const file_geecachepb_proto_rawDesc = "" +
 	"geecachepb\"1\n" +
 	"\aRequest\x12\x14\n" +
 	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\" \n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"m\n" +
 	"\bResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value2>\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x02 \x01(\x03R\tcreatedAt\x12\x12\n" +
+	"\x04node\x18\x03 \x01(\tR\x04node\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"/\n" +
+	"\x05Entry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\">\n" +
+	"\x0fBatchSetRequest\x12+\n" +
+	"\aentries\x18\x01 \x03(\v2\x11.geecachepb.EntryR\aentries\"*\n" +
+	"\x10BatchSetResponse\x12\x16\n" +
+	"\x06stored\x18\x01 \x01(\x03R\x06stored\"N\n" +
+	"\x0eBatchGetResult\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"L\n" +
+	"\x05Error\x12)\n" +
+	"\x04code\x18\x01 \x01(\x0e2\x15.geecachepb.ErrorCodeR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage*\x83\x02\n" +
+	"\tErrorCode\x12\f\n" +
+	"\bINTERNAL\x10\x00\x12\x0f\n" +
+	"\vBAD_REQUEST\x10\x01\x12\x13\n" +
+	"\x0fGROUP_NOT_FOUND\x10\x02\x12\x11\n" +
+	"\rKEY_NOT_FOUND\x10\x03\x12\x0f\n" +
+	"\vUNAVAILABLE\x10\x04\x12\x18\n" +
+	"\x14INSUFFICIENT_STORAGE\x10\x05\x12\x16\n" +
+	"\x12METHOD_NOT_ALLOWED\x10\x06\x12\x0e\n" +
 	"\n" +
-	"GroupCache\x120\n" +
-	"\x03Get\x12\x13.geecachepb.Request\x1a\x14.geecachepb.ResponseB\x15Z\x13geecache/geecachepbb\x06proto3"
+	"CACHE_MISS\x10\a\x12\x19\n" +
+	"\x15RANGE_NOT_SATISFIABLE\x10\b\x12\x13\n" +
+	"\x0fUNAUTHENTICATED\x10\t\x12\x15\n" +
+	"\x11PERMISSION_DENIED\x10\n" +
+	"\x12\x15\n" +
+	"\x11PAYLOAD_TOO_LARGE\x10\vB\x18Z\x16geecache/geecachepb;pbb\x06proto3"
 
 var (
 	file_geecachepb_proto_rawDescOnce sync.Once
@@ -144,19 +528,26 @@ func file_geecachepb_proto_rawDescGZIP() []byte {
 	return file_geecachepb_proto_rawDescData
 }
 
-var file_geecachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_geecachepb_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_geecachepb_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_geecachepb_proto_goTypes = []any{
-	(*Request)(nil),  // 0: geecachepb.Request
-	(*Response)(nil), // 1: geecachepb.Response
+	(ErrorCode)(0),           // 0: geecachepb.ErrorCode
+	(*Request)(nil),          // 1: geecachepb.Request
+	(*Response)(nil),         // 2: geecachepb.Response
+	(*Entry)(nil),            // 3: geecachepb.Entry
+	(*BatchSetRequest)(nil),  // 4: geecachepb.BatchSetRequest
+	(*BatchSetResponse)(nil), // 5: geecachepb.BatchSetResponse
+	(*BatchGetResult)(nil),   // 6: geecachepb.BatchGetResult
+	(*Error)(nil),            // 7: geecachepb.Error
 }
 var file_geecachepb_proto_depIdxs = []int32{
-	0, // 0: geecachepb.GroupCache.Get:input_type -> geecachepb.Request
-	1, // 1: geecachepb.GroupCache.Get:output_type -> geecachepb.Response
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	3, // 0: geecachepb.BatchSetRequest.entries:type_name -> geecachepb.Entry
+	0, // 1: geecachepb.Error.code:type_name -> geecachepb.ErrorCode
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
 }
 
 func init() { file_geecachepb_proto_init() }
@@ -169,13 +560,14 @@ func file_geecachepb_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_geecachepb_proto_rawDesc), len(file_geecachepb_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   2,
+			NumEnums:      1,
+			NumMessages:   7,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   0,
 		},
 		GoTypes:           file_geecachepb_proto_goTypes,
 		DependencyIndexes: file_geecachepb_proto_depIdxs,
+		EnumInfos:         file_geecachepb_proto_enumTypes,
 		MessageInfos:      file_geecachepb_proto_msgTypes,
 	}.Build()
 	File_geecachepb_proto = out.File
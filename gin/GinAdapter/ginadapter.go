@@ -0,0 +1,20 @@
+// Package ginadapter 将 HttpAddr 与指标端点适配为 gin 的处理函数。
+// 位于独立的 geecache/gin 模块中，只使用 net/http 的项目不会依赖 github.com/gin-gonic/gin。
+package ginadapter
+
+import (
+	httpserver "geecache/HttpServer"
+	metrics "geecache/Metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Serve 返回交给 p 处理请求的 gin.HandlerFunc，如 r.Any("/_geecache/*path", ginadapter.Serve(peers))
+func Serve(p *httpserver.HttpAddr) gin.HandlerFunc {
+	return gin.WrapH(p)
+}
+
+// MetricsHandler 是 metrics.Handler 的 gin 适配器，如 r.GET("/metrics", ginadapter.MetricsHandler())
+func MetricsHandler() gin.HandlerFunc {
+	return gin.WrapH(metrics.Handler())
+}
@@ -0,0 +1,43 @@
+package ginadapter
+
+import (
+	callbackfunc "geecache/CallbackFunc"
+	group "geecache/Group"
+	httpserver "geecache/HttpServer"
+	pb "geecache/geecachepb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestServe(t *testing.T) {
+	group.NewGroup("gin_adapter_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			return []byte("value-" + key), nil
+		}))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Any("/_geecache/*path", Serve(httpserver.NewHttpAddr("http://localhost:8001")))
+	r.GET("/metrics", MetricsHandler())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_geecache/gin_adapter_test/Tom", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	res := &pb.Response{}
+	if err := proto.Unmarshal(w.Body.Bytes(), res); err != nil || string(res.GetValue()) != "value-Tom" {
+		t.Fatalf("expected 'value-Tom', got %q, %v", res.GetValue(), err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "geecache") {
+		t.Fatalf("expected metrics output, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+// Package grpcclient 是基于 gRPC 的节点客户端，与 httpclient 一样实现 pickpeer.PeerGetter。
+// 位于独立的 geecache/grpc 模块中，只使用 HTTP 传输的项目不会依赖 google.golang.org/grpc。
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	pickpeer "geecache/PickPeer"
+	grpcpb "geecache/grpc/grpcpb"
+	pb "geecache/geecachepb"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+type GrpcClient struct {
+	// Addr 是远程节点的 gRPC 地址，如 "localhost:9001"
+	Addr string
+	// Timeout 限制每次 Get 的耗时，0 表示不限制
+	Timeout time.Duration
+
+	conn   *grpc.ClientConn
+	client grpcpb.GroupCacheClient
+}
+
+var (
+	_ pickpeer.PeerGetter        = (*GrpcClient)(nil)
+	_ pickpeer.PeerGetterContext = (*GrpcClient)(nil)
+)
+
+// Dial 建立到 addr 的连接，之后的所有请求复用该连接（HTTP/2 多路复用）。
+// 未传入 opts 时使用明文连接。
+func Dial(addr string, opts ...grpc.DialOption) (*GrpcClient, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GrpcClient{Addr: addr, conn: conn, client: grpcpb.NewGroupCacheClient(conn)}, nil
+}
+
+// String 返回节点的 gRPC 地址，用于日志与错误信息
+func (g *GrpcClient) String() string {
+	return g.Addr
+}
+
+func (g *GrpcClient) Get(in *pb.Request, out *pb.Response) error {
+	return g.GetContext(context.Background(), in, out)
+}
+
+// GetContext 与 Get 相同，ctx 的截止时间会随请求传给远程节点
+func (g *GrpcClient) GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error {
+	if g.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.Timeout)
+		defer cancel()
+	}
+	res, err := g.client.Get(ctx, in)
+	if err != nil {
+		return mapError(err)
+	}
+	out.Value = res.GetValue()
+	out.CreatedAt = res.GetCreatedAt()
+	out.Node = res.GetNode()
+	out.Version = res.GetVersion()
+	return nil
+}
+
+// mapError 与 httpclient.Error.Is 一样，把服务端标记为 KEY_NOT_FOUND 与 CACHE_MISS 的错误
+// 包装为 pickpeer.ErrNotFound 与 pickpeer.ErrCacheMiss，Group 据此判断远程节点是否已确认 key 不存在
+func mapError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.NotFound {
+		return err
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok {
+			continue
+		}
+		switch info.GetReason() {
+		case pb.ErrorCode_KEY_NOT_FOUND.String():
+			return fmt.Errorf("%w: %w", pickpeer.ErrNotFound, err)
+		case pb.ErrorCode_CACHE_MISS.String():
+			return fmt.Errorf("%w: %w", pickpeer.ErrCacheMiss, err)
+		}
+	}
+	return err
+}
+
+// Close 关闭底层连接
+func (g *GrpcClient) Close() error {
+	return g.conn.Close()
+}
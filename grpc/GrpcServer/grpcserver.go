@@ -0,0 +1,158 @@
+// Package grpcserver 是 HttpAddr 的 gRPC 版本：既为 Group 选择远程节点，也为其他节点提供 GroupCache 服务。
+// 位于独立的 geecache/grpc 模块中，只使用 HTTP 传输的项目不会依赖 google.golang.org/grpc。
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	consistenthash "geecache/ConsistentHash"
+	group "geecache/Group"
+	grpcclient "geecache/grpc/GrpcClient"
+	grpcpb "geecache/grpc/grpcpb"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultPeerTimeout 是节点间请求的默认超时时间
+const defaultPeerTimeout = 3 * time.Second
+
+type GrpcAddr struct {
+	grpcpb.UnimplementedGroupCacheServer
+
+	// Self 是本节点在哈希环中的地址
+	Self string
+	// PeerTimeout 是节点间每次请求的超时时间，0 使用默认的 3 秒，小于 0 表示不限制
+	PeerTimeout time.Duration
+	// DialOptions 用于连接远程节点，为空时使用明文连接
+	DialOptions []grpc.DialOption
+
+	mu      sync.Mutex
+	peers   *consistenthash.Map
+	clients map[string]*grpcclient.GrpcClient
+}
+
+var _ pickpeer.PeerPicker = (*GrpcAddr)(nil)
+
+func NewGrpcAddr(self string) *GrpcAddr {
+	return &GrpcAddr{Self: self}
+}
+
+// Set 更新节点列表。每个远程节点只建立一次连接并在之后复用，已移除节点的连接会被关闭。
+func (p *GrpcAddr) Set(peers ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	clients := make(map[string]*grpcclient.GrpcClient, len(peers))
+	for _, peer := range peers {
+		if peer == p.Self {
+			continue
+		}
+		if c, ok := p.clients[peer]; ok {
+			clients[peer] = c
+			continue
+		}
+		c, err := grpcclient.Dial(peer, p.DialOptions...)
+		if err != nil {
+			for addr, c := range clients {
+				if _, ok := p.clients[addr]; !ok {
+					c.Close()
+				}
+			}
+			return err
+		}
+		c.Timeout = p.timeout()
+		clients[peer] = c
+	}
+	for peer, c := range p.clients {
+		if _, ok := clients[peer]; !ok {
+			c.Close()
+		}
+	}
+	p.clients = clients
+	p.peers = consistenthash.New(consistenthash.DefaultReplicas, nil)
+	p.peers.AddKeys(peers...)
+	return nil
+}
+
+func (p *GrpcAddr) timeout() time.Duration {
+	switch {
+	case p.PeerTimeout == 0:
+		return defaultPeerTimeout
+	case p.PeerTimeout < 0:
+		return 0
+	}
+	return p.PeerTimeout
+}
+
+func (p *GrpcAddr) PickPeer(key string) (pickpeer.PeerGetter, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.peers == nil {
+		return nil, false
+	}
+	if peer := p.peers.Get(key); peer != "" && peer != p.Self {
+		if c, ok := p.clients[peer]; ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Register 在 s 上注册 GroupCache 服务
+func (p *GrpcAddr) Register(s *grpc.Server) {
+	grpcpb.RegisterGroupCacheServer(s, p)
+}
+
+// Get 实现 GroupCache 服务，返回本节点 Group 中 key 的值
+func (p *GrpcAddr) Get(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	g := group.GetGroup(in.GetGroup())
+	if g == nil {
+		return nil, statusError(codes.NotFound, pb.ErrorCode_GROUP_NOT_FOUND, "no such group: "+in.GetGroup())
+	}
+	bv, err := g.GetContext(ctx, in.GetKey())
+	if err != nil {
+		log.Printf("[GrpcServe on %s] Get %s/%s failed: %v", p.Self, in.GetGroup(), in.GetKey(), err)
+		var rejected *group.RejectedError
+		switch {
+		case errors.Is(err, group.ErrNotFound):
+			return nil, statusError(codes.NotFound, pb.ErrorCode_KEY_NOT_FOUND, err.Error())
+		case errors.Is(err, group.ErrCacheMiss):
+			return nil, statusError(codes.NotFound, pb.ErrorCode_CACHE_MISS, err.Error())
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			return nil, status.FromContextError(err).Err()
+		case errors.As(err, &rejected):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	meta := bv.Meta()
+	out := &pb.Response{
+		Value:   bv.ByteSlice(),
+		Node:    meta.Node,
+		Version: meta.Version,
+	}
+	if out.Node == "" {
+		out.Node = p.Self
+	}
+	if !meta.CreatedAt.IsZero() {
+		out.CreatedAt = meta.CreatedAt.UnixNano()
+	}
+	return out, nil
+}
+
+// statusError 返回状态码为 c 的错误，并以 ErrorInfo 附带 pb.ErrorCode，
+// 使 GrpcClient 能区分 group 不存在、key 不存在与缓存未命中
+func statusError(c codes.Code, code pb.ErrorCode, msg string) error {
+	st := status.New(c, msg)
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: code.String(), Domain: "geecache"}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
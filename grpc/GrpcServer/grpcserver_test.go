@@ -0,0 +1,102 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	callbackfunc "geecache/CallbackFunc"
+	group "geecache/Group"
+	grpcclient "geecache/grpc/GrpcClient"
+	pickpeer "geecache/PickPeer"
+	pb "geecache/geecachepb"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialBufconn 在内存连接上启动 addr 的 GroupCache 服务，返回连接到它的 GrpcClient
+func dialBufconn(t *testing.T, addr *GrpcAddr) *grpcclient.GrpcClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	addr.Register(s)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	c, err := grpcclient.Dial("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestGrpc_RoundTrip(t *testing.T) {
+	group.NewGroup("grpc_round_trip_test", 2<<10, callbackfunc.CallbackFunc(
+		func(key string) ([]byte, error) {
+			if key == "missing" {
+				return nil, group.ErrNotFound
+			}
+			return []byte("value-" + key), nil
+		}))
+	c := dialBufconn(t, NewGrpcAddr("bufnet"))
+
+	res := &pb.Response{}
+	if err := c.Get(&pb.Request{Group: "grpc_round_trip_test", Key: "k"}, res); err != nil {
+		t.Fatal(err)
+	}
+	if string(res.GetValue()) != "value-k" || res.GetNode() != "bufnet" || res.GetCreatedAt() == 0 {
+		t.Fatalf("unexpected response %+v", res)
+	}
+
+	// key 不存在时映射为 ErrNotFound，Group 据此写入负缓存而不是回源
+	err := c.Get(&pb.Request{Group: "grpc_round_trip_test", Key: "missing"}, &pb.Response{})
+	if !errors.Is(err, pickpeer.ErrNotFound) || !errors.Is(err, group.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	// group 不存在不是 key 不存在
+	err = c.Get(&pb.Request{Group: "no_such_group", Key: "k"}, &pb.Response{})
+	if status.Code(err) != codes.NotFound || errors.Is(err, pickpeer.ErrNotFound) {
+		t.Fatalf("expected a plain NotFound status, got %v", err)
+	}
+}
+
+func TestGrpc_CacheMiss(t *testing.T) {
+	group.NewGroup("grpc_cache_miss_test", 2<<10, nil, group.WithoutLoader())
+	c := dialBufconn(t, NewGrpcAddr("bufnet"))
+	err := c.Get(&pb.Request{Group: "grpc_cache_miss_test", Key: "k"}, &pb.Response{})
+	if !errors.Is(err, pickpeer.ErrCacheMiss) || errors.Is(err, pickpeer.ErrNotFound) {
+		t.Fatalf("expected ErrCacheMiss, got %v", err)
+	}
+}
+
+func TestGrpc_ContextCanceled(t *testing.T) {
+	canceled := make(chan error, 1)
+	group.NewGroupContext("grpc_ctx_test", 2<<10, callbackfunc.CallbackFuncCtx(
+		func(ctx context.Context, key string) ([]byte, error) {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+			return nil, ctx.Err()
+		}))
+	c := dialBufconn(t, NewGrpcAddr("bufnet"))
+	c.Timeout = 20 * time.Millisecond
+
+	err := c.Get(&pb.Request{Group: "grpc_ctx_test", Key: "k"}, &pb.Response{})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	// 服务端的加载随 RPC 的 ctx 一起取消
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("server-side load should be canceled with the RPC")
+	}
+}
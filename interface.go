@@ -5,6 +5,7 @@
 package geecache
 
 import (
+	"context"
 	cache "geecache/Cache"
 	pb "geecache/geecachepb"
 )
@@ -23,6 +24,8 @@ type Cacher interface {
 	Add(key string, value cache.ByteView)
 }
 
+var _ Cacher = (*cache.Cache)(nil)
+
 // =============================================================================
 // 节点选择接口
 // =============================================================================
@@ -44,6 +47,37 @@ type PeerGetter interface {
 	Get(in *pb.Request, out *pb.Response) error
 }
 
+// PeerGetterContext 定义支持取消的远程节点获取接口
+type PeerGetterContext interface {
+	// GetContext 与 PeerGetter.Get 相同，ctx 结束时取消请求
+	GetContext(ctx context.Context, in *pb.Request, out *pb.Response) error
+}
+
+// ReplicaPicker 定义多副本节点选择接口
+type ReplicaPicker interface {
+	// PickPeers 按哈希环顺序返回 key 的至多 n 个远程节点（不含自身）
+	PickPeers(key string, n int) (peers []PeerGetter)
+}
+
+// PeerDeleter 定义删除远程节点数据的接口
+type PeerDeleter interface {
+	// Delete 删除远程节点缓存中的 key
+	Delete(in *pb.Request) error
+}
+
+// PreviousOwnerPicker 定义哈希环变更期间查找旧归属节点的接口
+type PreviousOwnerPicker interface {
+	// PickPreviousOwner 在哈希环变更后的窗口期内返回 key 变更前的归属节点
+	// 旧节点已下线、是自身或已过窗口期时返回 nil, false
+	PickPreviousOwner(key string) (peer PeerGetter, ok bool)
+}
+
+// PeerSetter 定义向远程节点写入数据的接口
+type PeerSetter interface {
+	// Set 将 value 写入远程节点的缓存
+	Set(in *pb.Request, value []byte) error
+}
+
 // =============================================================================
 // 数据源回调接口
 // =============================================================================